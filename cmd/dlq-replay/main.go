@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/Moreira-Henrique-Pedro/entregador/config"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	watermillKafka "github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+const defaultMaxReplays = 3
+
+type replayOptions struct {
+	Topic         string
+	ConsumerGroup string
+	EventType     string
+	Key           string
+	ErrorContains string
+	Since         time.Time
+	Until         time.Time
+	MaxReplays    int
+	Limit         int
+	IdleTimeout   time.Duration
+	DryRun        bool
+}
+
+type replayStats struct {
+	Read     int
+	Replayed int
+	Skipped  int
+}
+
+type replayOutcome int
+
+const (
+	outcomeReplayed replayOutcome = iota
+	outcomeSkipped
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	envs, err := config.ReadEnvs()
+	if err != nil {
+		log.Fatalf("failed to read environment variables: %v", err)
+	}
+
+	opts, err := parseFlags(envs)
+	if err != nil {
+		log.Fatalf("invalid flags: %v", err)
+	}
+
+	logger, err := appLogger.NewLogrusLogger("delivery-dlq-replay", envs.App.Env, envs.App.LogLevel)
+	if err != nil {
+		log.Fatalf("failed to create logger: %v", err)
+	}
+	ctx = logger.AddToContext(ctx, logger)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName:    "delivery-dlq-replay",
+		ServiceVersion: envs.App.Version,
+		Environment:    envs.App.Env,
+		Exporter:       envs.Tracing.Exporter,
+		FilePath:       envs.Tracing.FilePath,
+		OTLPEndpoint:   envs.Tracing.OTLPEndpoint,
+		OTLPInsecure:   envs.Tracing.OTLPInsecure,
+		SampleRatio:    envs.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("failed to setup tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("Failed to shutdown tracing", "error", err.Error())
+		}
+	}()
+
+	stats, err := run(ctx, envs, opts, logger)
+
+	logger.Info("DLQ replay finished",
+		"dry_run", opts.DryRun,
+		"read", stats.Read,
+		"replayed", stats.Replayed,
+		"skipped", stats.Skipped,
+	)
+
+	if err != nil {
+		logger.Error("DLQ replay failed", "error", err.Error())
+		_ = shutdownTracing(context.Background())
+		stop()
+		os.Exit(1)
+	}
+}
+
+func parseFlags(envs *config.Environment) (*replayOptions, error) {
+	opts := &replayOptions{}
+	var since, until string
+
+	flag.StringVar(&opts.Topic, "topic", envs.Pubsub.DLQTopic, "DLQ topic to read from")
+	flag.StringVar(&opts.ConsumerGroup, "consumer-group", "", "consumer group used to commit progress; when empty the whole DLQ is read and nothing is committed. "+
+		"It cannot be combined with filters, since committing past the messages they exclude would hide them from later runs")
+	flag.StringVar(&opts.EventType, "event-type", "", "only replay messages with this event type")
+	flag.StringVar(&opts.Key, "key", "", "only replay messages with this key")
+	flag.StringVar(&opts.ErrorContains, "error-contains", "", "only replay messages whose failure reason or errors contain this substring")
+	flag.StringVar(&since, "since", "", "only replay messages that failed at or after this RFC3339 time")
+	flag.StringVar(&until, "until", "", "only replay messages that failed before this RFC3339 time")
+	flag.IntVar(&opts.MaxReplays, "max-replays", defaultMaxReplays, "skip messages that were already replayed this many times")
+	flag.IntVar(&opts.Limit, "limit", 0, "stop after replaying this many messages (0 means no limit)")
+	flag.DurationVar(&opts.IdleTimeout, "idle-timeout", 30*time.Second, "stop after this long without receiving a message")
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "print what would be replayed without publishing or committing offsets")
+	flag.Parse()
+
+	var err error
+	if since != "" {
+		if opts.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("parse since: %w", err)
+		}
+	}
+	if until != "" {
+		if opts.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return nil, fmt.Errorf("parse until: %w", err)
+		}
+	}
+
+	if opts.DryRun {
+		opts.ConsumerGroup = ""
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// validate rejects filters combined with a consumer group: Watermill only delivers the next message of a
+// partition once the current one is acked, so the run could neither skip an excluded message without
+// committing past it nor leave it uncommitted without stopping there.
+func (o *replayOptions) validate() error {
+	if o.Topic == "" {
+		return errors.New("topic cannot be empty")
+	}
+	if o.ConsumerGroup != "" && o.hasFilters() {
+		return errors.New("filters cannot be combined with a consumer group; replay without -consumer-group to scan the whole DLQ")
+	}
+	return nil
+}
+
+func (o *replayOptions) hasFilters() bool {
+	return o.EventType != "" || o.Key != "" || o.ErrorContains != "" || !o.Since.IsZero() || !o.Until.IsZero()
+}
+
+func run(ctx context.Context, envs *config.Environment, opts *replayOptions, logger appLogger.Logger) (replayStats, error) {
+	stats := replayStats{}
+
+	subscriber, err := createDLQSubscriber(envs, opts, logger)
+	if err != nil {
+		return stats, fmt.Errorf("create dlq subscriber: %w", err)
+	}
+	defer func() {
+		if err := subscriber.Close(); err != nil {
+			logger.Error("Failed to close DLQ subscriber", "error", err.Error())
+		}
+	}()
+
+	var publisher pubsub.MessagePublisher[any]
+	if !opts.DryRun {
+		publisher, err = appWatermill.NewWatermillPublisher[any](envs.Pubsub.DeliveryBrokersHosts, nil, logger)
+		if err != nil {
+			return stats, fmt.Errorf("create publisher: %w", err)
+		}
+		defer func() {
+			if err := publisher.Close(context.Background()); err != nil {
+				logger.Error("Failed to close publisher", "error", err.Error())
+			}
+		}()
+	}
+
+	messages, err := subscriber.Subscribe(ctx, opts.Topic)
+	if err != nil {
+		return stats, fmt.Errorf("subscribe to topic %s: %w", opts.Topic, err)
+	}
+
+	logger.Info("DLQ replay started",
+		"topic", opts.Topic,
+		"consumer_group", opts.ConsumerGroup,
+		"dry_run", opts.DryRun,
+	)
+
+	return replay(ctx, messages, publisher, opts, logger)
+}
+
+// replay republishes messages until ctx is cancelled, messages is closed, the limit is reached or no message
+// arrives within the idle timeout.
+func replay(
+	ctx context.Context,
+	messages <-chan *watermillMessage.Message,
+	publisher pubsub.MessagePublisher[any],
+	opts *replayOptions,
+	logger appLogger.Logger,
+) (replayStats, error) {
+	stats := replayStats{}
+
+	idleTimer := time.NewTimer(opts.IdleTimeout)
+	defer idleTimer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return stats, nil
+		case <-idleTimer.C:
+			logger.Info("No DLQ messages received within idle timeout", "idle_timeout", opts.IdleTimeout.String())
+			return stats, nil
+		case msg, ok := <-messages:
+			if !ok {
+				return stats, nil
+			}
+			stats.Read++
+
+			outcome, err := replayMessage(ctx, publisher, opts, msg, logger)
+			if err != nil {
+				msg.Nack()
+				return stats, err
+			}
+			msg.Ack()
+
+			if outcome == outcomeReplayed {
+				stats.Replayed++
+			} else {
+				stats.Skipped++
+			}
+
+			if opts.Limit > 0 && stats.Replayed >= opts.Limit {
+				return stats, nil
+			}
+
+			if !idleTimer.Stop() {
+				<-idleTimer.C
+			}
+			idleTimer.Reset(opts.IdleTimeout)
+		}
+	}
+}
+
+func createDLQSubscriber(envs *config.Environment, opts *replayOptions, logger appLogger.Logger) (*watermillKafka.Subscriber, error) {
+	saramaConfig := watermillKafka.DefaultSaramaSubscriberConfig()
+	saramaConfig.ClientID = "delivery_dlq_replay"
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	return watermillKafka.NewSubscriber(
+		watermillKafka.SubscriberConfig{
+			Brokers:               envs.Pubsub.DeliveryBrokersHosts,
+			ConsumerGroup:         opts.ConsumerGroup,
+			OverwriteSaramaConfig: saramaConfig,
+			Unmarshaler:           appWatermill.NewWatermillMarshaler(),
+		},
+		appWatermill.NewWatermillLoggerFromLogger(logger),
+	)
+}
+
+func replayMessage(
+	ctx context.Context,
+	publisher pubsub.MessagePublisher[any],
+	opts *replayOptions,
+	msg *watermillMessage.Message,
+	logger appLogger.Logger,
+) (replayOutcome, error) {
+	messageLogger := logger.With("message_uuid", msg.UUID)
+
+	dlqMessage, err := appWatermill.ConvertWatermillToPubsub(msg, nil)
+	if err != nil {
+		messageLogger.Warn("Skipping DLQ message that cannot be converted", "error", err.Error())
+		return outcomeSkipped, nil
+	}
+
+	headers := dlqMessage.Headers
+	messageLogger = messageLogger.With(
+		"event_type", headers.EventType,
+		"message_key", headers.Key,
+		"replay_count", headers.ReplayCount,
+	)
+
+	if reason := skipReason(opts, headers); reason != "" {
+		messageLogger.Debug("Skipping DLQ message", "reason", reason)
+		return outcomeSkipped, nil
+	}
+	if reason := filterReason(opts, headers); reason != "" {
+		messageLogger.Debug("DLQ message excluded by filters", "reason", reason)
+		return outcomeSkipped, nil
+	}
+
+	originalTopic := *headers.OriginalTopic
+	messageLogger = messageLogger.With("original_topic", originalTopic)
+
+	if opts.DryRun {
+		messageLogger.Info("Dry run: would replay DLQ message",
+			"failure_reason", headers.DeadLetter.FailureReason,
+			"failed_at", headers.DeadLetter.FailedAt,
+			"errors", headers.DeadLetter.Errors,
+		)
+		return outcomeReplayed, nil
+	}
+
+	republished := pubsub.NewMessage(ctx, pubsub.Headers{
+		MessageID:     headers.MessageID,
+		CorrelationID: headers.CorrelationID,
+		CausationID:   headers.CausationID,
+		EventType:     headers.EventType,
+		EventVersion:  headers.EventVersion,
+		Key:           headers.Key,
+		Source:        headers.Source,
+		ContentType:   headers.ContentType,
+		ReplayCount:   headers.ReplayCount + 1,
+		TraceContext:  headers.TraceContext,
+	}, dlqMessage.Payload.Data)
+
+	if err := publisher.Publish(ctx, originalTopic, republished); err != nil {
+		return outcomeReplayed, fmt.Errorf("replay message %s to topic %s: %w", msg.UUID, originalTopic, err)
+	}
+
+	messageLogger.Info("DLQ message replayed")
+	return outcomeReplayed, nil
+}
+
+// skipReason reports why a message can never be replayed.
+func skipReason(opts *replayOptions, headers pubsub.Headers) string {
+	deadLetter := headers.DeadLetter
+
+	switch {
+	case headers.OriginalTopic == nil || *headers.OriginalTopic == "":
+		return "missing original topic"
+	case deadLetter == nil:
+		return "missing dead letter headers"
+	case deadLetter.FailureReason == pubsub.FailureReasonUnconvertiblePayload:
+		return "payload cannot be replayed"
+	case headers.ReplayCount >= opts.MaxReplays:
+		return "max replays reached"
+	}
+
+	return ""
+}
+
+// filterReason reports why a replayable message is excluded by the filters of this run.
+func filterReason(opts *replayOptions, headers pubsub.Headers) string {
+	deadLetter := headers.DeadLetter
+
+	switch {
+	case opts.EventType != "" && headers.EventType != opts.EventType:
+		return "event type does not match"
+	case opts.Key != "" && headers.Key != opts.Key:
+		return "key does not match"
+	case !opts.Since.IsZero() && deadLetter.FailedAt.Before(opts.Since):
+		return "failed before time window"
+	case !opts.Until.IsZero() && !deadLetter.FailedAt.Before(opts.Until):
+		return "failed after time window"
+	case opts.ErrorContains != "" && !errorMatches(deadLetter, opts.ErrorContains):
+		return "errors do not match"
+	}
+
+	return ""
+}
+
+func errorMatches(deadLetter *pubsub.DeadLetter, substring string) bool {
+	if strings.Contains(deadLetter.FailureReason, substring) {
+		return true
+	}
+	for _, err := range deadLetter.Errors {
+		if strings.Contains(err, substring) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+func TestSkipAndFilterReasons(t *testing.T) {
+	failedAt := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	originalTopic := "resident-management.events"
+
+	replayable := func() pubsub.Headers {
+		return pubsub.Headers{
+			EventType:     "CreateResident",
+			Key:           "key-1",
+			OriginalTopic: &originalTopic,
+			DeadLetter: &pubsub.DeadLetter{
+				FailureReason: pubsub.FailureReasonRetriesExhausted,
+				FailedAt:      failedAt,
+				Errors:        []string{"mongo timeout"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		opts       replayOptions
+		headers    func() pubsub.Headers
+		wantSkip   bool
+		wantFilter bool
+	}{
+		{
+			name:    "replayable without filters",
+			opts:    replayOptions{MaxReplays: 3},
+			headers: replayable,
+		},
+		{
+			name: "missing original topic",
+			opts: replayOptions{MaxReplays: 3},
+			headers: func() pubsub.Headers {
+				headers := replayable()
+				headers.OriginalTopic = nil
+				return headers
+			},
+			wantSkip: true,
+		},
+		{
+			name: "unconvertible payload",
+			opts: replayOptions{MaxReplays: 3},
+			headers: func() pubsub.Headers {
+				headers := replayable()
+				headers.DeadLetter.FailureReason = pubsub.FailureReasonUnconvertiblePayload
+				return headers
+			},
+			wantSkip: true,
+		},
+		{
+			name: "max replays reached",
+			opts: replayOptions{MaxReplays: 3},
+			headers: func() pubsub.Headers {
+				headers := replayable()
+				headers.ReplayCount = 3
+				return headers
+			},
+			wantSkip: true,
+		},
+		{
+			name:       "event type does not match",
+			opts:       replayOptions{MaxReplays: 3, EventType: "DeleteResident"},
+			headers:    replayable,
+			wantFilter: true,
+		},
+		{
+			name:       "failed before the window",
+			opts:       replayOptions{MaxReplays: 3, Since: failedAt.Add(time.Minute)},
+			headers:    replayable,
+			wantFilter: true,
+		},
+		{
+			name:       "failed at the end of the window",
+			opts:       replayOptions{MaxReplays: 3, Until: failedAt},
+			headers:    replayable,
+			wantFilter: true,
+		},
+		{
+			name:    "errors match",
+			opts:    replayOptions{MaxReplays: 3, ErrorContains: "timeout"},
+			headers: replayable,
+		},
+		{
+			name:       "errors do not match",
+			opts:       replayOptions{MaxReplays: 3, ErrorContains: "duplicate key"},
+			headers:    replayable,
+			wantFilter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := tt.headers()
+
+			if got := skipReason(&tt.opts, headers) != ""; got != tt.wantSkip {
+				t.Errorf("skipped = %v, want %v", got, tt.wantSkip)
+			}
+			if tt.wantSkip {
+				return
+			}
+			if got := filterReason(&tt.opts, headers) != ""; got != tt.wantFilter {
+				t.Errorf("filtered = %v, want %v", got, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestReplayOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    replayOptions
+		wantErr bool
+	}{
+		{name: "whole DLQ", opts: replayOptions{Topic: "dlq"}},
+		{name: "filters without consumer group", opts: replayOptions{Topic: "dlq", EventType: "CreateResident"}},
+		{name: "consumer group without filters", opts: replayOptions{Topic: "dlq", ConsumerGroup: "replay"}},
+		{name: "consumer group with filters", opts: replayOptions{Topic: "dlq", ConsumerGroup: "replay", Key: "key-1"}, wantErr: true},
+		{name: "consumer group with time window", opts: replayOptions{Topic: "dlq", ConsumerGroup: "replay", Since: time.Now()}, wantErr: true},
+		{name: "empty topic", opts: replayOptions{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type stubPublisher struct {
+	published []string
+}
+
+func (p *stubPublisher) Publish(_ context.Context, topic string, messages ...*pubsub.Message[any]) error {
+	for _, msg := range messages {
+		p.published = append(p.published, topic+"/"+msg.Headers.Key)
+	}
+	return nil
+}
+
+func (p *stubPublisher) Close(context.Context) error { return nil }
+
+func TestReplayKeepsScanningPastFilteredMessages(t *testing.T) {
+	originalTopic := "resident-management.events"
+	keys := []string{"key-2", "key-1", "key-2", "key-1"}
+
+	messages := make(chan *watermillMessage.Message, len(keys))
+	for _, key := range keys {
+		headers := pubsub.NewHeaders("CreateResident", key)
+		headers.OriginalTopic = &originalTopic
+		headers.DeadLetter = &pubsub.DeadLetter{
+			FailureReason: pubsub.FailureReasonRetriesExhausted,
+			FailedAt:      time.Now(),
+		}
+
+		msg, err := appWatermill.ConvertPubsubToWatermill(context.Background(), "dlq", pubsub.NewMessage[any](context.Background(), headers, map[string]any{}), nil, appLogger.NewNoopLogger())
+		if err != nil {
+			t.Fatalf("convert message: %v", err)
+		}
+		messages <- msg
+	}
+	close(messages)
+
+	publisher := &stubPublisher{}
+	opts := &replayOptions{Topic: "dlq", Key: "key-1", MaxReplays: defaultMaxReplays, IdleTimeout: time.Second}
+
+	stats, err := replay(context.Background(), messages, publisher, opts, appLogger.NewNoopLogger())
+	if err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+
+	want := replayStats{Read: 4, Replayed: 2, Skipped: 2}
+	if stats != want {
+		t.Errorf("replay() stats = %+v, want %+v", stats, want)
+	}
+	wantPublished := []string{originalTopic + "/key-1", originalTopic + "/key-1"}
+	if !reflect.DeepEqual(publisher.published, wantPublished) {
+		t.Errorf("published = %v, want %v", publisher.published, wantPublished)
+	}
+}
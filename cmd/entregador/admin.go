@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/health"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+)
+
+const adminReadHeaderTimeout = 5 * time.Second
+
+// newAdminServer builds the operational HTTP server, kept apart from any business endpoint. The read-only
+// endpoints are open to probes and scrapers; the consumer control ones require token, see requireOperator.
+func newAdminServer(addr, token string, readiness, liveness *health.Checker, consumers []*Consumer) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.Handle("GET /readyz", readiness.Handler())
+	mux.Handle("GET /healthz", liveness.Handler())
+	mux.Handle("GET /consumers", consumersHandler(consumers))
+	mux.Handle("POST /consumers/pause", requireOperator(token, consumerControlHandler(consumers, func(consumer *Consumer) bool {
+		return consumer.Pause(pauseReasonOperator)
+	})))
+	mux.Handle("POST /consumers/resume", requireOperator(token, consumerControlHandler(consumers, func(consumer *Consumer) bool {
+		return consumer.Resume(pauseReasonOperator)
+	})))
+	mux.Handle("POST /consumers/drain", requireOperator(token, consumerControlHandler(consumers, (*Consumer).Drain)))
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+	}
+}
+
+func (a *Application) startAdminServer() {
+	go func() {
+		a.Logger.Info("Admin HTTP server started", "addr", a.AdminServer.Addr)
+		if err := a.AdminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.Logger.Error("Admin HTTP server stopped with error", "error", err.Error())
+		}
+	}()
+}
+
+// requireOperator only lets requests carrying token as a bearer token through. Without a token configured,
+// only requests from a loopback address are accepted.
+func requireOperator(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			if !isLoopback(r.RemoteAddr) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "consumer control is only allowed from localhost"})
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type consumerControlResult struct {
+	consumerStatus
+	Changed bool `json:"changed"`
+}
+
+// consumerControlHandler applies action to every consumer, or only to the ones of the topic query parameter.
+func consumerControlHandler(consumers []*Consumer, action func(*Consumer) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		topic := r.URL.Query().Get("topic")
+
+		results := make([]consumerControlResult, 0, len(consumers))
+		for _, consumer := range consumers {
+			if topic != "" && consumer.Config.Topic != topic {
+				continue
+			}
+			changed := action(consumer)
+			results = append(results, consumerControlResult{consumerStatus: newConsumerStatus(consumer), Changed: changed})
+		}
+
+		if len(results) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no consumer for topic " + topic})
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireOperator(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		remoteAddr    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "no token from localhost", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusOK},
+		{name: "no token from ipv6 localhost", remoteAddr: "[::1]:5000", wantStatus: http.StatusOK},
+		{name: "no token from another host", remoteAddr: "10.0.0.5:5000", wantStatus: http.StatusForbidden},
+		{name: "valid token", token: "secret", remoteAddr: "10.0.0.5:5000", authorization: "Bearer secret", wantStatus: http.StatusOK},
+		{name: "wrong token", token: "secret", remoteAddr: "127.0.0.1:5000", authorization: "Bearer other", wantStatus: http.StatusUnauthorized},
+		{name: "missing token", token: "secret", remoteAddr: "127.0.0.1:5000", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := requireOperator(tt.token, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			request := httptest.NewRequest(http.MethodPost, "/consumers/pause", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/Moreira-Henrique-Pedro/entregador/config/subscriber"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/circuitbreaker"
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/retry"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	watermillKafka "github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Consumer runs a single subscription: one topic, one consumer group and one handler set.
+type Consumer struct {
+	Config       *subscriber.SubscriberConfig
+	Logger       appLogger.Logger
+	EventBus     *pkgEvents.EventBus
+	Registry     *pkgEvents.EventHandlerRegistry
+	Subscriber   watermillMessage.Subscriber
+	DLQPublisher pubsub.MessagePublisher[any]
+	DLQTopic     string
+	// Monitor follows the consumer group session; nil when the subscriber is not Kafka.
+	Monitor *appWatermill.ConsumerGroupMonitor
+	// CircuitBreakers names the breakers of the dependencies the handlers call; the consumer is paused
+	// while one of them is open.
+	CircuitBreakers []string
+
+	flow     *flowControl
+	inFlight sync.Map
+}
+
+// inFlightMessage is a message handed to a worker and not yet acked or nacked.
+type inFlightMessage struct {
+	UUID      string
+	EventType string
+	Key       string
+	StartedAt time.Time
+}
+
+func createKafkaSubscriber(
+	brokers []string,
+	subscriberCfg *subscriber.SubscriberConfig,
+	monitor *appWatermill.ConsumerGroupMonitor,
+	logger appLogger.Logger,
+) (*watermillKafka.Subscriber, error) {
+	saramaConfig := watermillKafka.DefaultSaramaSubscriberConfig()
+	saramaConfig.ClientID = subscriberCfg.ConsumerName
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	return watermillKafka.NewSubscriber(
+		watermillKafka.SubscriberConfig{
+			Brokers:               brokers,
+			ConsumerGroup:         subscriberCfg.ConsumerGroup,
+			OverwriteSaramaConfig: saramaConfig,
+			Unmarshaler:           appWatermill.NewWatermillMarshaler(),
+			Tracer:                monitor,
+		},
+		appWatermill.NewWatermillLoggerFromLogger(logger),
+	)
+}
+
+func (c *Consumer) Run(ctx context.Context) error {
+	defer c.flow.markStopped()
+
+	// The subscription outlives ctx and is only cancelled once the worker pool has stopped, so the messages
+	// still in flight on shutdown are acked while the consumer group session is alive and their offsets are
+	// committed when it is released.
+	subscriptionCtx, cancelSubscription := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelSubscription()
+
+	messages, err := c.Subscriber.Subscribe(subscriptionCtx, c.Config.Topic)
+	if err != nil {
+		return fmt.Errorf("subscribe to topic %s: %w", c.Config.Topic, err)
+	}
+	c.flow.markSubscribed()
+
+	pool := newWorkerPool(c.Config.Concurrency, func(msg *watermillMessage.Message) {
+		c.handleKafkaMessage(ctx, msg)
+	})
+	defer pool.Stop()
+
+	c.Logger.Info("Kafka consumer started",
+		"concurrency", c.Config.Concurrency,
+		"registered_handlers", c.Registry.GetAllEventTypes(),
+	)
+
+	for {
+		state, changed := c.flow.snapshot()
+		if state == consumerStateDraining {
+			c.Logger.Info("Kafka consumer drained, waiting for in-flight messages")
+			return nil
+		}
+
+		// A Kafka consumer is paused at the partitions, and keeps reading so a rebalance is never blocked on a
+		// message the loop does not take.
+		input := messages
+		if c.Monitor != nil {
+			c.Monitor.SetPaused(state == consumerStatePaused)
+		} else if state == consumerStatePaused {
+			input = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case msg, ok := <-input:
+			if !ok {
+				return nil
+			}
+
+			metrics.MessagesReceived.WithLabelValues(c.metricLabels(msg)...).Inc()
+
+			key := msg.Metadata.Get(pubsub.KeyHeader)
+			if key == "" {
+				key = msg.UUID
+			}
+
+			if !pool.Dispatch(ctx, key, msg) {
+				return nil
+			}
+		}
+	}
+}
+
+// Pause stops fetching messages without leaving the consumer group until Resume is called with the same reason.
+func (c *Consumer) Pause(reason string) bool {
+	if !c.flow.Pause(reason) {
+		return false
+	}
+	c.Logger.Info("Kafka consumer paused", "reason", reason)
+	return true
+}
+
+func (c *Consumer) Resume(reason string) bool {
+	if !c.flow.Resume(reason) {
+		return false
+	}
+	c.Logger.Info("Kafka consumer resumed", "reason", reason, "state", c.State())
+	return true
+}
+
+// Drain stops fetching and lets Run return once the in-flight messages are processed.
+func (c *Consumer) Drain() bool {
+	if !c.flow.Drain() {
+		return false
+	}
+	c.Logger.Info("Kafka consumer draining")
+	return true
+}
+
+func (c *Consumer) DependsOn(circuitBreaker string) bool {
+	return slices.Contains(c.CircuitBreakers, circuitBreaker)
+}
+
+func (c *Consumer) State() string {
+	return c.flow.State()
+}
+
+// InFlight returns the messages currently being processed.
+func (c *Consumer) InFlight() []inFlightMessage {
+	var messages []inFlightMessage
+	c.inFlight.Range(func(_, value any) bool {
+		messages = append(messages, value.(inFlightMessage))
+		return true
+	})
+	return messages
+}
+
+// Subscribed is closed once Run has subscribed to the topic.
+func (c *Consumer) Subscribed() <-chan struct{} {
+	return c.flow.Subscribed()
+}
+
+// Stopped is closed once Run has returned.
+func (c *Consumer) Stopped() <-chan struct{} {
+	return c.flow.Stopped()
+}
+
+// CheckAssignment fails while the Kafka consumer has not joined a consumer group session, for instance
+// during a rebalance.
+func (c *Consumer) CheckAssignment(ctx context.Context) error {
+	if c.Monitor == nil || c.Monitor.Assigned() {
+		return nil
+	}
+	return errors.New("consumer group session not established")
+}
+
+// CheckProgress fails when a claimed partition has lag but no message was processed within stallTimeout.
+func (c *Consumer) CheckProgress(stallTimeout time.Duration) error {
+	// A paused or stopped consumer makes no progress on purpose.
+	if c.Monitor == nil || c.State() != consumerStateRunning {
+		return nil
+	}
+
+	stalled := c.Monitor.StalledPartitions(stallTimeout)
+	if len(stalled) == 0 {
+		return nil
+	}
+
+	partitions := make([]string, 0, len(stalled))
+	for _, status := range stalled {
+		partitions = append(partitions, fmt.Sprintf("%s[%d] lag=%d idle=%s",
+			status.Topic, status.Partition, status.Lag, time.Since(status.LastProgress).Truncate(time.Second)))
+	}
+	return fmt.Errorf("consume loop stalled: %s", strings.Join(partitions, ", "))
+}
+
+func (c *Consumer) Close() error {
+	if c.Subscriber == nil {
+		return nil
+	}
+	return c.Subscriber.Close()
+}
+
+// handleKafkaMessage always acks or nacks msg. ctx is cancelled on shutdown, which stops retries between
+// attempts; an attempt already running and the DLQ publish are not interrupted.
+func (c *Consumer) handleKafkaMessage(ctx context.Context, msg *watermillMessage.Message) {
+	labels := c.metricLabels(msg)
+
+	c.inFlight.Store(msg.UUID, inFlightMessage{
+		UUID:      msg.UUID,
+		EventType: msg.Metadata.Get(pubsub.EventTypeHeader),
+		Key:       msg.Metadata.Get(pubsub.KeyHeader),
+		StartedAt: time.Now(),
+	})
+	defer c.inFlight.Delete(msg.UUID)
+
+	if err := c.processMessage(ctx, msg); err != nil {
+		c.Logger.Error("Failed to process Kafka message",
+			"error", err.Error(),
+			"message_uuid", msg.UUID,
+		)
+
+		var dlErr *deadLetterError
+		if !errors.As(err, &dlErr) {
+			metrics.MessagesFailed.WithLabelValues(labels...).Inc()
+			msg.Nack()
+			return
+		}
+
+		if err := c.publishDeadLetter(context.WithoutCancel(ctx), msg, dlErr); err != nil {
+			c.Logger.Error("Failed to publish Kafka message to DLQ",
+				"error", err.Error(),
+				"message_uuid", msg.UUID,
+			)
+			metrics.MessagesFailed.WithLabelValues(labels...).Inc()
+			msg.Nack()
+			return
+		}
+
+		metrics.MessagesDeadLettered.WithLabelValues(append(labels, dlErr.reason)...).Inc()
+		msg.Ack()
+		return
+	}
+
+	metrics.MessagesHandled.WithLabelValues(labels...).Inc()
+	msg.Ack()
+}
+
+func (c *Consumer) metricLabels(msg *watermillMessage.Message) []string {
+	return []string{c.Config.Topic, c.Config.ConsumerGroup, msg.Metadata.Get(pubsub.EventTypeHeader)}
+}
+
+func (c *Consumer) processMessage(ctx context.Context, kafkaMessage *watermillMessage.Message) (err error) {
+	pubsubMessage, convertErr := appWatermill.ConvertWatermillToPubsub(kafkaMessage, nil)
+	if convertErr == nil {
+		ctx = tracing.Extract(ctx, pubsubMessage.Headers.TraceContext)
+	}
+
+	ctx, span := c.startProcessSpan(ctx, kafkaMessage)
+	defer func() { tracing.End(span, err) }()
+
+	if convertErr != nil {
+		return newDeadLetterError(pubsub.FailureReasonUnconvertiblePayload, fmt.Errorf("convert kafka message: %w", convertErr))
+	}
+	span.SetAttributes(attribute.String("messaging.event_type", pubsubMessage.Headers.EventType))
+
+	if pubsubMessage.Headers.EventType == "" {
+		return newDeadLetterError(pubsub.FailureReasonMissingEventType, fmt.Errorf("message has no %s header", pubsub.EventTypeHeader))
+	}
+
+	ctx = pkgEvents.WithMessageHeaders(ctx, pubsubMessage.Headers)
+
+	messageLogger := c.Logger.With(
+		"message_uuid", kafkaMessage.UUID,
+		"message_id", pubsubMessage.Headers.MessageID,
+		"correlation_id", pkgEvents.CorrelationIDFromContext(ctx),
+		"causation_id", pubsubMessage.Headers.CausationID,
+		"event_type", pubsubMessage.Headers.EventType,
+		"event_version", pubsubMessage.Headers.EventVersion,
+		"message_key", pubsubMessage.Headers.Key,
+		"trace_id", span.SpanContext().TraceID().String(),
+	)
+	ctx = messageLogger.AddToContext(ctx, messageLogger)
+
+	retryPolicy := c.Config.RetryConfig.Policy()
+	retryPolicy.Retryable = pkgEvents.IsRetryable
+
+	err = retry.Do(ctx, retryPolicy, func(attemptCtx context.Context) error {
+		return c.handleMessage(attemptCtx, pubsubMessage, messageLogger)
+	})
+
+	var exhaustedErr *retry.ExhaustedError
+	if errors.As(err, &exhaustedErr) {
+		// The dependency is unavailable rather than the message being bad: leave it on the broker for when
+		// the circuit closes.
+		if errors.Is(exhaustedErr.Last(), circuitbreaker.ErrOpen) {
+			return err
+		}
+		return newDeadLetterError(pubsub.FailureReasonRetriesExhausted, exhaustedErr)
+	}
+
+	var abortedErr *retry.AbortedError
+	if errors.As(err, &abortedErr) {
+		var panicErr *pkgEvents.PanicError
+		if errors.As(abortedErr, &panicErr) {
+			return newDeadLetterError(pubsub.FailureReasonPanic, abortedErr)
+		}
+		var validationErr *pkgEvents.ValidationError
+		if errors.As(abortedErr, &validationErr) {
+			return newDeadLetterError(pubsub.FailureReasonInvalidPayload, abortedErr)
+		}
+		if errors.Is(abortedErr, pkgEvents.ErrEventTypeNotRegistered) {
+			return newDeadLetterError(pubsub.FailureReasonUnknownEventType, abortedErr)
+		}
+		return newDeadLetterError(pubsub.FailureReasonPermanentError, abortedErr)
+	}
+	return err
+}
+
+func (c *Consumer) startProcessSpan(ctx context.Context, kafkaMessage *watermillMessage.Message) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingDestinationName(c.Config.Topic),
+		semconv.MessagingConsumerGroupName(c.Config.ConsumerGroup),
+		semconv.MessagingMessageID(kafkaMessage.UUID),
+	}
+	if partition, ok := watermillKafka.MessagePartitionFromCtx(kafkaMessage.Context()); ok {
+		attributes = append(attributes, semconv.MessagingDestinationPartitionID(strconv.Itoa(int(partition))))
+	}
+	if offset, ok := watermillKafka.MessagePartitionOffsetFromCtx(kafkaMessage.Context()); ok {
+		attributes = append(attributes, semconv.MessagingKafkaOffset(int(offset)))
+	}
+
+	return tracing.Start(ctx, "process "+c.Config.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func (c *Consumer) handleMessage(ctx context.Context, pubsubMessage *pubsub.Message[any], messageLogger appLogger.Logger) error {
+	// The attempt runs to completion under its own timeout even if shutdown begins meanwhile.
+	messageCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.Config.TimeOut.Duration())
+	defer cancel()
+
+	attempt := retry.AttemptFromContext(ctx)
+	if attempt > 1 {
+		metrics.MessagesRetried.WithLabelValues(c.Config.Topic, c.Config.ConsumerGroup, pubsubMessage.Headers.EventType).Inc()
+	}
+
+	attemptLogger := messageLogger.With("attempt", attempt)
+	messageCtx = attemptLogger.AddToContext(messageCtx, attemptLogger)
+
+	attemptLogger.Info("Processing Kafka message")
+
+	if err := c.EventBus.Handle(messageCtx, pubsubMessage); err != nil {
+		return fmt.Errorf("handle event bus message: %w", err)
+	}
+
+	attemptLogger.Info("Kafka message processed successfully")
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	partitionLabels = []string{"topic", "consumer_group", "partition"}
+
+	consumerLagDesc = metrics.NewDesc("consumer_lag",
+		"Messages between the committed offset and the high-water mark of a claimed partition.", partitionLabels)
+	highWaterMarkDesc = metrics.NewDesc("consumer_high_water_mark",
+		"High-water mark of a claimed partition.", partitionLabels)
+	committedOffsetDesc = metrics.NewDesc("consumer_committed_offset",
+		"Next offset to consume of a claimed partition.", partitionLabels)
+	assignedPartitionsDesc = metrics.NewDesc("consumer_assigned_partitions",
+		"Partitions claimed by the consumer group member.", []string{"topic", "consumer_group"})
+	consumerPausedDesc = metrics.NewDesc("consumer_paused",
+		"1 while consumption is paused by an operator.", []string{"topic", "consumer_group"})
+)
+
+// consumerLagCollector reads the consumer group monitors at scrape time, so revoked partitions disappear
+// from the exported series as soon as a rebalance takes them away.
+type consumerLagCollector struct {
+	consumers []*Consumer
+}
+
+func (c *consumerLagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- consumerLagDesc
+	ch <- highWaterMarkDesc
+	ch <- committedOffsetDesc
+	ch <- assignedPartitionsDesc
+	ch <- consumerPausedDesc
+}
+
+func (c *consumerLagCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, consumer := range c.consumers {
+		paused := 0.0
+		if consumer.State() == consumerStatePaused {
+			paused = 1
+		}
+		ch <- prometheus.MustNewConstMetric(consumerPausedDesc, prometheus.GaugeValue,
+			paused, consumer.Config.Topic, consumer.Config.ConsumerGroup)
+
+		if consumer.Monitor == nil {
+			continue
+		}
+
+		status := consumer.Monitor.Status()
+		ch <- prometheus.MustNewConstMetric(assignedPartitionsDesc, prometheus.GaugeValue,
+			float64(len(status.Partitions)), consumer.Config.Topic, consumer.Config.ConsumerGroup)
+
+		for _, partition := range status.Partitions {
+			labels := []string{partition.Topic, consumer.Config.ConsumerGroup, strconv.Itoa(int(partition.Partition))}
+			ch <- prometheus.MustNewConstMetric(highWaterMarkDesc, prometheus.GaugeValue, float64(partition.HighWaterMark), labels...)
+			// No lag is exported for a partition that has no committed offset yet.
+			if !partition.OffsetKnown {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(consumerLagDesc, prometheus.GaugeValue, float64(partition.Lag), labels...)
+			ch <- prometheus.MustNewConstMetric(committedOffsetDesc, prometheus.GaugeValue, float64(partition.CommittedOffset), labels...)
+		}
+	}
+}
+
+type consumerStatus struct {
+	Topic         string   `json:"topic"`
+	ConsumerGroup string   `json:"consumer_group"`
+	State         string   `json:"state"`
+	PauseReasons  []string `json:"pause_reasons,omitempty"`
+	// GroupStatus is only reported for Kafka consumers.
+	*appWatermill.GroupStatus
+}
+
+func newConsumerStatus(consumer *Consumer) consumerStatus {
+	status := consumerStatus{
+		Topic:         consumer.Config.Topic,
+		ConsumerGroup: consumer.Config.ConsumerGroup,
+		State:         consumer.State(),
+		PauseReasons:  consumer.flow.PauseReasons(),
+	}
+	if consumer.Monitor != nil {
+		groupStatus := consumer.Monitor.Status()
+		status.GroupStatus = &groupStatus
+	}
+	return status
+}
+
+// consumersHandler reports the state, assignment and lag of every consumer.
+func consumersHandler(consumers []*Consumer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := make([]consumerStatus, 0, len(consumers))
+		for _, consumer := range consumers {
+			statuses = append(statuses, newConsumerStatus(consumer))
+		}
+
+		writeJSON(w, http.StatusOK, statuses)
+	})
+}
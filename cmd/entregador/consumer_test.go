@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/config"
+	"github.com/Moreira-Henrique-Pedro/entregador/config/subscriber"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/application/commands"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/providers"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/circuitbreaker"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/duration"
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+// stubSubscriber hands out a single subscription and keeps its context.
+type stubSubscriber struct {
+	messages      chan *watermillMessage.Message
+	subscribeCtxs chan context.Context
+}
+
+func (s *stubSubscriber) Subscribe(ctx context.Context, _ string) (<-chan *watermillMessage.Message, error) {
+	s.subscribeCtxs <- ctx
+	return s.messages, nil
+}
+
+func (s *stubSubscriber) Close() error { return nil }
+
+type stubEvent struct {
+	ID string `json:"id"`
+}
+
+func TestConsumerKeepsSubscriptionUntilInFlightMessagesAreAcked(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	registry := pkgEvents.NewEventHandlerRegistry()
+	err := registry.RegisterHandler("stub.event", func(context.Context, any) error {
+		close(started)
+		<-release
+		return nil
+	}, reflect.TypeOf(stubEvent{}))
+	if err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+
+	sub := &stubSubscriber{
+		messages:      make(chan *watermillMessage.Message, 1),
+		subscribeCtxs: make(chan context.Context, 1),
+	}
+	consumer := &Consumer{
+		Config: &subscriber.SubscriberConfig{
+			Topic:         "stub-topic",
+			ConsumerGroup: "stub-group",
+			TimeOut:       duration.Duration(time.Second),
+			RetryConfig:   &subscriber.RetryConfig{MaxRetries: 0, Multiplier: 1},
+			Concurrency:   1,
+		},
+		Logger:     appLogger.NewNoopLogger(),
+		EventBus:   pkgEvents.NewEventBus(pkgEvents.EventBusDependencies{EventHandlerRegistry: registry}),
+		Registry:   registry,
+		Subscriber: sub,
+		flow:       newFlowControl(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = consumer.Run(ctx) }()
+	subscriptionCtx := <-sub.subscribeCtxs
+
+	payload, _ := json.Marshal(map[string]any{"data": stubEvent{ID: "1"}})
+	msg := watermillMessage.NewMessage("message-1", payload)
+	msg.Metadata.Set(pubsub.EventTypeHeader, "stub.event")
+	sub.messages <- msg
+	<-started
+
+	// The shutdown signal arrives while the message is being handled.
+	cancel()
+	consumer.Drain()
+	if err := subscriptionCtx.Err(); err != nil {
+		t.Fatalf("subscription cancelled with a message in flight: %v", err)
+	}
+
+	close(release)
+	select {
+	case <-msg.Acked():
+	case <-time.After(time.Second):
+		t.Fatal("message was not acked")
+	}
+	select {
+	case <-consumer.Stopped():
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop")
+	}
+	if subscriptionCtx.Err() == nil {
+		t.Error("subscription not cancelled after the consumer stopped")
+	}
+}
+
+func TestPauseOnOpenCircuits(t *testing.T) {
+	newBreaker := func(name string, openTimeout time.Duration, probe func(context.Context) error) *circuitbreaker.Breaker {
+		return circuitbreaker.New(circuitbreaker.Config{
+			Name:             name,
+			FailureThreshold: 1,
+			OpenTimeout:      openTimeout,
+			Probe:            probe,
+		}, appLogger.NewNoopLogger())
+	}
+	newConsumer := func(topic string, breakers ...string) *Consumer {
+		return &Consumer{
+			Config:          &subscriber.SubscriberConfig{Topic: topic},
+			Logger:          appLogger.NewNoopLogger(),
+			CircuitBreakers: breakers,
+			flow:            newFlowControl(),
+		}
+	}
+
+	probes := make(chan error)
+	publisherBreaker := newBreaker("publisher", time.Hour, nil)
+	mongoBreaker := newBreaker("mongodb", time.Millisecond, func(context.Context) error { return <-probes })
+	transporters := newConsumer("events", "mongodb", "publisher")
+	writers := newConsumer("commands", "mongodb")
+
+	app := &Application{
+		ServiceProviders: &providers.ServiceProviders{PublisherBreaker: publisherBreaker, MongoBreaker: mongoBreaker},
+		Consumers:        []*Consumer{transporters, writers},
+	}
+	app.pauseOnOpenCircuits()
+
+	_ = publisherBreaker.Execute(func() error { return errors.New("broker down") })
+	if transporters.State() != consumerStatePaused || writers.State() != consumerStateRunning {
+		t.Fatalf("publisher open: transporters %s, writers %s; want paused and running", transporters.State(), writers.State())
+	}
+
+	_ = mongoBreaker.Execute(func() error { return errors.New("mongodb down") })
+	if writers.State() != consumerStatePaused {
+		t.Fatalf("mongodb open: writers %s, want paused", writers.State())
+	}
+
+	// Writers stay paused while the breaker probes MongoDB, and resume once the probe closes it.
+	waitFor(t, func() bool { return mongoBreaker.State() == circuitbreaker.StateHalfOpen })
+	if writers.State() != consumerStatePaused {
+		t.Fatalf("mongodb half-open: writers %s, want paused", writers.State())
+	}
+	probes <- nil
+	waitFor(t, func() bool { return writers.State() == consumerStateRunning })
+}
+
+// breakerResidentRepository fails every insert through breaker, as the MongoDB client does while the
+// database is down.
+type breakerResidentRepository struct {
+	breaker *circuitbreaker.Breaker
+}
+
+func (r *breakerResidentRepository) Insert(context.Context, *entities.Resident) error {
+	return r.breaker.Execute(func() error { return errors.New("mongodb down") })
+}
+
+func TestConsumerLeavesMessagesOnTheBrokerWhileTheBreakerIsOpen(t *testing.T) {
+	tests := []struct {
+		name             string
+		failureThreshold int
+		wantDeadLetter   bool
+	}{
+		// The first attempt opens the breaker and the retry is rejected by it.
+		{name: "breaker opens inside the writer", failureThreshold: 1},
+		{name: "breaker stays closed", failureThreshold: 10, wantDeadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := circuitbreaker.New(circuitbreaker.Config{
+				Name:             "mongodb",
+				FailureThreshold: tt.failureThreshold,
+				OpenTimeout:      time.Hour,
+			}, appLogger.NewNoopLogger())
+
+			writerProviders, err := providers.NewWriterProviders(&config.Environment{}, &providers.ServiceProviders{
+				ResidentRepository: &breakerResidentRepository{breaker: breaker},
+			})
+			if err != nil {
+				t.Fatalf("create writer providers: %v", err)
+			}
+
+			consumer := &Consumer{
+				Config: &subscriber.SubscriberConfig{
+					Topic:       "commands",
+					TimeOut:     duration.Duration(time.Second),
+					RetryConfig: &subscriber.RetryConfig{MaxRetries: 1, Multiplier: 1},
+				},
+				Logger:   appLogger.NewNoopLogger(),
+				EventBus: pkgEvents.NewEventBus(pkgEvents.EventBusDependencies{EventHandlerRegistry: writerProviders.Registry}),
+				Registry: writerProviders.Registry,
+			}
+
+			payload, _ := json.Marshal(map[string]any{"data": commands.ProcessCreateResidentCommand{
+				CommandID: "command-1",
+				Name:      "Ana",
+				Apartment: "101",
+			}})
+			msg := watermillMessage.NewMessage("message-1", payload)
+			msg.Metadata.Set(pubsub.EventTypeHeader, commands.ProcessCreateResidentCommandType)
+
+			err = consumer.processMessage(context.Background(), msg)
+
+			var deadLetterErr *deadLetterError
+			if errors.As(err, &deadLetterErr) != tt.wantDeadLetter {
+				t.Fatalf("processMessage() error = %v, want dead-lettered %v", err, tt.wantDeadLetter)
+			}
+			if !tt.wantDeadLetter && !errors.Is(err, circuitbreaker.ErrOpen) {
+				t.Errorf("processMessage() error = %v, want it to wrap %v", err, circuitbreaker.ErrOpen)
+			}
+		})
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
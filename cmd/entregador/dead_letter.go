@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/retry"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	watermillKafka "github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+// deadLetterError marks a failure that must not be redelivered and is routed to the DLQ instead.
+type deadLetterError struct {
+	reason   string
+	attempts int
+	errs     []error
+}
+
+func newDeadLetterError(reason string, err error) *deadLetterError {
+	var exhaustedErr *retry.ExhaustedError
+	if errors.As(err, &exhaustedErr) {
+		return &deadLetterError{reason: reason, attempts: exhaustedErr.Attempts, errs: exhaustedErr.Errors}
+	}
+
+	var abortedErr *retry.AbortedError
+	if errors.As(err, &abortedErr) {
+		return &deadLetterError{reason: reason, attempts: abortedErr.Attempts, errs: abortedErr.Errors}
+	}
+	return &deadLetterError{reason: reason, attempts: 1, errs: []error{err}}
+}
+
+func (e *deadLetterError) Error() string {
+	return fmt.Sprintf("%s after %d attempts: %v", e.reason, e.attempts, errors.Join(e.errs...))
+}
+
+func (e *deadLetterError) Unwrap() []error {
+	return e.errs
+}
+
+func (c *Consumer) publishDeadLetter(ctx context.Context, msg *watermillMessage.Message, dlErr *deadLetterError) error {
+	dlqMessage, convertErr := appWatermill.ConvertWatermillToPubsub(msg, nil)
+	if convertErr != nil {
+		dlqMessage = appWatermill.BuildRawDLQMessage(msg, dlErr, convertErr)
+	}
+
+	originalTopic := c.Config.Topic
+	dlqMessage.Headers.OriginalTopic = &originalTopic
+	dlqMessage.Headers.DeadLetter = c.buildDeadLetterHeaders(msg, dlErr)
+
+	if err := c.DLQPublisher.Publish(ctx, c.DLQTopic, dlqMessage); err != nil {
+		return fmt.Errorf("publish message %s to dlq topic %s: %w", msg.UUID, c.DLQTopic, err)
+	}
+
+	c.Logger.Warn("Kafka message sent to DLQ",
+		"message_uuid", msg.UUID,
+		"dlq_topic", c.DLQTopic,
+		"dlq_cluster", c.Config.DLQCluster,
+		"failure_reason", dlErr.reason,
+		"attempts", dlErr.attempts,
+	)
+	return nil
+}
+
+func (c *Consumer) buildDeadLetterHeaders(msg *watermillMessage.Message, dlErr *deadLetterError) *pubsub.DeadLetter {
+	errorStack := make([]string, 0, len(dlErr.errs))
+	for _, err := range dlErr.errs {
+		errorStack = append(errorStack, err.Error())
+
+		var panicErr *pkgEvents.PanicError
+		if errors.As(err, &panicErr) {
+			errorStack = append(errorStack, string(panicErr.Stack))
+		}
+	}
+
+	deadLetter := &pubsub.DeadLetter{
+		OriginalPartition: -1,
+		OriginalOffset:    -1,
+		ConsumerGroup:     c.Config.ConsumerGroup,
+		FailureReason:     dlErr.reason,
+		AttemptCount:      dlErr.attempts,
+		Errors:            errorStack,
+		FailedAt:          time.Now().UTC(),
+	}
+
+	if partition, ok := watermillKafka.MessagePartitionFromCtx(msg.Context()); ok {
+		deadLetter.OriginalPartition = partition
+	}
+	if offset, ok := watermillKafka.MessagePartitionOffsetFromCtx(msg.Context()); ok {
+		deadLetter.OriginalOffset = offset
+	}
+
+	return deadLetter
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sync"
+)
+
+const (
+	consumerStateRunning  = "running"
+	consumerStatePaused   = "paused"
+	consumerStateDraining = "draining"
+	consumerStateStopped  = "stopped"
+)
+
+// pauseReasonOperator is used for pauses requested through the admin API or signals.
+const pauseReasonOperator = "operator"
+
+// flowControl holds the state of a consumer loop. Pausing only stops fetching: the consumer group session
+// stays alive, so no rebalance is triggered and the claimed partitions are kept until consumption resumes,
+// unless another member joins or leaves. Pauses are tracked per reason, and the loop only resumes once every
+// reason that paused it has been lifted.
+type flowControl struct {
+	mu         sync.Mutex
+	state      string
+	pauses     map[string]bool
+	changed    chan struct{}
+	subscribed chan struct{}
+	stopped    chan struct{}
+}
+
+func newFlowControl() *flowControl {
+	return &flowControl{
+		state:      consumerStateRunning,
+		pauses:     make(map[string]bool),
+		changed:    make(chan struct{}),
+		subscribed: make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// snapshot returns the current state and a channel closed on the next state change.
+func (f *flowControl) snapshot() (string, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.currentState(), f.changed
+}
+
+func (f *flowControl) State() string {
+	state, _ := f.snapshot()
+	return state
+}
+
+// PauseReasons lists why the loop is paused.
+func (f *flowControl) PauseReasons() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reasons := make([]string, 0, len(f.pauses))
+	for reason := range f.pauses {
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// Pause reports whether reason was not already pausing a running loop.
+func (f *flowControl) Pause(reason string) bool {
+	return f.update(func() bool {
+		if f.state != consumerStateRunning || f.pauses[reason] {
+			return false
+		}
+		f.pauses[reason] = true
+		return true
+	})
+}
+
+func (f *flowControl) Resume(reason string) bool {
+	return f.update(func() bool {
+		if !f.pauses[reason] {
+			return false
+		}
+		delete(f.pauses, reason)
+		return true
+	})
+}
+
+// Drain stops fetching new messages, even while paused; the loop exits once the in-flight ones are finished.
+func (f *flowControl) Drain() bool {
+	return f.update(func() bool {
+		if f.state != consumerStateRunning {
+			return false
+		}
+		f.state = consumerStateDraining
+		return true
+	})
+}
+
+// Subscribed is closed once the consumer loop has subscribed to its topic.
+func (f *flowControl) Subscribed() <-chan struct{} {
+	return f.subscribed
+}
+
+func (f *flowControl) markSubscribed() {
+	close(f.subscribed)
+}
+
+// Stopped is closed once the consumer loop has returned.
+func (f *flowControl) Stopped() <-chan struct{} {
+	return f.stopped
+}
+
+func (f *flowControl) markStopped() {
+	f.update(func() bool {
+		f.state = consumerStateStopped
+		return true
+	})
+	close(f.stopped)
+}
+
+func (f *flowControl) currentState() string {
+	if f.state == consumerStateRunning && len(f.pauses) > 0 {
+		return consumerStatePaused
+	}
+	return f.state
+}
+
+func (f *flowControl) update(apply func() bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !apply() {
+		return false
+	}
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return true
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/config"
+	"github.com/Moreira-Henrique-Pedro/entregador/config/subscriber"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/application/events"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/providers"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories/memory"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/duration"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+)
+
+const (
+	residentEventsTopic = "resident-management.events"
+	flowTimeout         = 5 * time.Second
+)
+
+// startMemoryApplication runs both subscriptions of the service against the memory pub/sub and stores.
+func startMemoryApplication(t *testing.T, outboxEnabled bool) *Application {
+	t.Helper()
+
+	envs := &config.Environment{}
+	envs.Pubsub.Driver = config.PubsubDriverMemory
+	envs.Pubsub.DLQTopic = "delivery-subscriber.dlq"
+	envs.Storage.Driver = config.StorageDriverMemory
+	envs.Outbox.Enabled = outboxEnabled
+	envs.Outbox.PollInterval = 10 * time.Millisecond
+	envs.Outbox.BatchSize = 10
+	envs.Outbox.MaxAttempts = 3
+	envs.Outbox.LeaseDuration = time.Second
+	envs.Shutdown.GracePeriod = flowTimeout
+	envs.Shutdown.CloseTimeout = flowTimeout
+
+	retryConfig := &subscriber.RetryConfig{
+		MaxRetries:      1,
+		InitialInterval: duration.Duration(time.Millisecond),
+		MaxInterval:     duration.Duration(time.Millisecond),
+		Multiplier:      1,
+	}
+	subscriptions := []*subscriber.SubscriberConfig{
+		{
+			App:           "delivery-subscriber",
+			ConsumerGroup: "resident-management-events",
+			ConsumerName:  "resident_management_events",
+			Topic:         residentEventsTopic,
+			Handlers:      subscriber.HandlersTransporters,
+			TimeOut:       duration.Duration(flowTimeout),
+			RetryConfig:   retryConfig,
+			Concurrency:   1,
+		},
+		{
+			App:           "delivery-subscriber",
+			ConsumerGroup: "internal-commands",
+			ConsumerName:  "internal_commands",
+			Topic:         internalCommandsTopic,
+			Handlers:      subscriber.HandlersWriters,
+			TimeOut:       duration.Duration(flowTimeout),
+			RetryConfig:   retryConfig,
+			Concurrency:   1,
+			Decoding:      "strict",
+		},
+	}
+
+	logger := appLogger.NewNoopLogger()
+	serviceProviders, err := providers.NewServiceProviders(envs, logger)
+	if err != nil {
+		t.Fatalf("create service providers: %v", err)
+	}
+
+	app := &Application{
+		Configs: &config.AppConfigs{
+			Envs:              envs,
+			SubscriberConfigs: &subscriber.Config{App: "delivery-subscriber", Subscriptions: subscriptions},
+		},
+		Logger:           logger,
+		ServiceProviders: serviceProviders,
+	}
+	for _, subscriberCfg := range subscriptions {
+		consumer, err := createConsumer(app, subscriberCfg)
+		if err != nil {
+			t.Fatalf("create consumer for topic %s: %v", subscriberCfg.Topic, err)
+		}
+		app.Consumers = append(app.Consumers, consumer)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if serviceProviders.OutboxRelay != nil {
+		go serviceProviders.OutboxRelay.Run(ctx)
+	}
+	go func() { _ = runApplication(ctx, app) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := shutdownApplication(app); err != nil {
+			t.Errorf("shutdown application: %v", err)
+		}
+	})
+
+	for _, consumer := range app.Consumers {
+		select {
+		case <-consumer.Subscribed():
+		case <-time.After(flowTimeout):
+			t.Fatalf("consumer for topic %s did not subscribe", consumer.Config.Topic)
+		}
+	}
+	return app
+}
+
+func publishCreateResident(t *testing.T, app *Application, messageID string, event *events.CreateResident) {
+	t.Helper()
+
+	headers := pubsub.NewHeaders(events.CreateResidentEventType, event.Apartment)
+	headers.MessageID = messageID
+	message := pubsub.NewMessage[any](context.Background(), headers, event)
+
+	if err := app.ServiceProviders.MessagePublisher.Publish(context.Background(), residentEventsTopic, message); err != nil {
+		t.Fatalf("publish CreateResident: %v", err)
+	}
+}
+
+func TestCreateResidentFlow(t *testing.T) {
+	for _, outboxEnabled := range []bool{false, true} {
+		name := "direct publish"
+		if outboxEnabled {
+			name = "through the outbox"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			app := startMemoryApplication(t, outboxEnabled)
+			residents := app.ServiceProviders.ResidentRepository.(*memory.ResidentRepository)
+
+			event := &events.CreateResident{Name: "Ana", Apartment: "101", Phone: "+5511999999999"}
+			publishCreateResident(t, app, "event-1", event)
+			// A redelivery must not create a second resident.
+			publishCreateResident(t, app, "event-1", event)
+
+			deadline := time.Now().Add(flowTimeout)
+			for len(residents.List()) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			// Leave time for the redelivery to be handled too.
+			time.Sleep(100 * time.Millisecond)
+
+			stored := residents.List()
+			if len(stored) != 1 {
+				t.Fatalf("residents = %+v, want exactly one", stored)
+			}
+			if resident := stored[0]; resident.Name != event.Name || resident.Apartment != event.Apartment || resident.Phone != event.Phone {
+				t.Errorf("resident = %+v, want %+v", resident, event)
+			}
+		})
+	}
+}
+
+func TestCreateResidentFlowDeadLettersInvalidEvents(t *testing.T) {
+	app := startMemoryApplication(t, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), flowTimeout)
+	defer cancel()
+	deadLetters, err := app.ServiceProviders.MemoryPubSub.Subscribe(ctx, app.Configs.Envs.Pubsub.DLQTopic)
+	if err != nil {
+		t.Fatalf("subscribe to dlq: %v", err)
+	}
+
+	publishCreateResident(t, app, "event-2", &events.CreateResident{Apartment: "102"})
+
+	select {
+	case msg := <-deadLetters:
+		msg.Ack()
+		dlqMessage, err := appWatermill.ConvertWatermillToPubsub(msg, nil)
+		if err != nil {
+			t.Fatalf("convert dlq message: %v", err)
+		}
+		if reason := dlqMessage.Headers.DeadLetter.FailureReason; reason != pubsub.FailureReasonInvalidPayload {
+			t.Errorf("failure reason = %s, want %s", reason, pubsub.FailureReasonInvalidPayload)
+		}
+	case <-ctx.Done():
+		t.Fatal("invalid event was not dead-lettered")
+	}
+
+	if stored := app.ServiceProviders.ResidentRepository.(*memory.ResidentRepository).List(); len(stored) != 0 {
+		t.Errorf("residents = %+v, want none", stored)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/config"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/health"
+	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+)
+
+// buildHealthCheckers returns the readiness and liveness checkers served on /readyz and /healthz.
+func buildHealthCheckers(app *Application) (readiness, liveness *health.Checker) {
+	envs := app.Configs.Envs
+	readiness = health.NewChecker(envs.Health.CheckTimeout)
+	liveness = health.NewChecker(envs.Health.CheckTimeout)
+
+	if envs.Pubsub.Driver == config.PubsubDriverKafka {
+		readiness.Add("kafka_brokers", kafkaBrokersCheck(envs.Pubsub.DeliveryBrokersHosts))
+	}
+	if envs.Storage.Driver == config.StorageDriverMongoDB {
+		readiness.Add("mongodb", app.ServiceProviders.PingMongo)
+	}
+	readiness.Add("publisher", app.ServiceProviders.CheckPublisher)
+
+	for _, consumer := range app.Consumers {
+		name := "consumer:" + consumer.Config.Topic + "/" + consumer.Config.ConsumerGroup
+		readiness.Add(name, consumer.CheckAssignment)
+		liveness.Add(name, func(context.Context) error {
+			return consumer.CheckProgress(envs.Health.StallTimeout)
+		})
+	}
+
+	return readiness, liveness
+}
+
+// kafkaBrokersCheck passes when at least one broker accepts a connection.
+func kafkaBrokersCheck(brokers []string) health.Check {
+	return func(ctx context.Context) error {
+		return appWatermill.PingBrokers(ctx, brokers)
+	}
+}
@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/IBM/sarama"
 	"github.com/Moreira-Henrique-Pedro/entregador/config"
+	"github.com/Moreira-Henrique-Pedro/entregador/config/subscriber"
 	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/providers"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/circuitbreaker"
 	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
 	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
 	appWatermill "github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
-	watermillKafka "github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
 	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
 )
 
@@ -23,41 +29,13 @@ var version = "dev"
 const internalCommandsTopic = "delivery-internal.commands"
 
 type Application struct {
-	Configs              *config.AppConfigs
-	Logger               appLogger.Logger
-	ServiceProviders     *providers.ServiceProviders
-	TransporterProviders *providers.TransporterProviders
-	WriterProviders      *providers.WriterProviders
-	EventBus             *pkgEvents.EventBus
-	Registry             *pkgEvents.EventHandlerRegistry
-	Subscriber           *watermillKafka.Subscriber
-}
-
-func processMessage(ctx context.Context, app *Application, kafkaMessage *watermillMessage.Message) error {
-	messageCtx, cancel := context.WithTimeout(ctx, app.Configs.SubscriberConfigs.TimeOut.Duration())
-	defer cancel()
-
-	pubsubMessage, err := appWatermill.ConvertWatermillToPubsub(kafkaMessage, nil)
-	if err != nil {
-		return fmt.Errorf("convert kafka message: %w", err)
-	}
-
-	messageLogger := app.Logger.With(
-		"message_uuid", kafkaMessage.UUID,
-		"event_type", pubsubMessage.Headers.EventType,
-		"message_key", pubsubMessage.Headers.Key,
-		"topic", app.Configs.SubscriberConfigs.Topic,
-	)
-	messageCtx = messageLogger.AddToContext(messageCtx, messageLogger)
-
-	messageLogger.Info("Processing Kafka message")
-
-	if err := app.EventBus.Handle(messageCtx, pubsubMessage); err != nil {
-		return fmt.Errorf("handle event bus message: %w", err)
-	}
-
-	messageLogger.Info("Kafka message processed successfully")
-	return nil
+	Configs          *config.AppConfigs
+	Logger           appLogger.Logger
+	ShutdownTracing  tracing.ShutdownFunc
+	ServiceProviders *providers.ServiceProviders
+	WriterProviders  *providers.WriterProviders
+	Consumers        []*Consumer
+	AdminServer      *http.Server
 }
 
 func main() {
@@ -71,11 +49,16 @@ func main() {
 
 	app.Logger.Info("Application initialized",
 		"version", version,
-		"topic", app.Configs.SubscriberConfigs.Topic,
-		"consumer_group", app.Configs.SubscriberConfigs.ConsumerGroup,
-		"registered_handlers", app.Registry.GetAllEventTypes(),
+		"subscriptions", len(app.Consumers),
 	)
 
+	app.startAdminServer()
+	go app.handleControlSignals(ctx)
+
+	if app.ServiceProviders.OutboxRelay != nil {
+		go app.ServiceProviders.OutboxRelay.Run(ctx)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- runApplication(ctx, app)
@@ -91,10 +74,7 @@ func main() {
 		stop()
 	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err := shutdownApplication(shutdownCtx, app); err != nil {
+	if err := shutdownApplication(app); err != nil {
 		app.Logger.Error("Application shutdown finished with errors", "error", err.Error())
 		return
 	}
@@ -121,129 +101,300 @@ func initializeApplication(ctx context.Context) (*Application, error) {
 
 	ctx = logger.AddToContext(ctx, logger)
 
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName:    appConfigs.Envs.App.Name,
+		ServiceVersion: version,
+		Environment:    appConfigs.Envs.App.Env,
+		Exporter:       appConfigs.Envs.Tracing.Exporter,
+		FilePath:       appConfigs.Envs.Tracing.FilePath,
+		OTLPEndpoint:   appConfigs.Envs.Tracing.OTLPEndpoint,
+		OTLPInsecure:   appConfigs.Envs.Tracing.OTLPInsecure,
+		SampleRatio:    appConfigs.Envs.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup tracing: %w", err)
+	}
+
 	serviceProviders, err := providers.NewServiceProviders(appConfigs.Envs, logger)
 	if err != nil {
 		return nil, fmt.Errorf("create service providers: %w", err)
 	}
 
-	transporterProviders, err := providers.NewTransporterProviders(appConfigs.Envs, appConfigs.SubscriberConfigs, serviceProviders)
-	if err != nil {
-		return nil, fmt.Errorf("create transporter providers: %w", err)
+	app := &Application{
+		Configs:          appConfigs,
+		Logger:           logger,
+		ShutdownTracing:  shutdownTracing,
+		ServiceProviders: serviceProviders,
 	}
 
-	var (
-		writerProviders *providers.WriterProviders
-		registry        *pkgEvents.EventHandlerRegistry
-	)
+	for _, subscriberCfg := range appConfigs.SubscriberConfigs.Subscriptions {
+		if subscriberCfg.TimeOut.Duration() > appConfigs.Envs.Shutdown.GracePeriod {
+			logger.Warn("Subscription timeout exceeds the shutdown grace period, in-flight messages may be abandoned",
+				"topic", subscriberCfg.Topic,
+				"timeout", subscriberCfg.TimeOut.Duration().String(),
+				"grace_period", appConfigs.Envs.Shutdown.GracePeriod.String(),
+			)
+		}
 
-	if appConfigs.SubscriberConfigs.Topic == internalCommandsTopic {
-		writerProviders, err = providers.NewWriterProviders(appConfigs.Envs, serviceProviders)
+		consumer, err := createConsumer(app, subscriberCfg)
 		if err != nil {
-			return nil, fmt.Errorf("create writer providers: %w", err)
+			return nil, fmt.Errorf("create consumer for topic %s: %w", subscriberCfg.Topic, err)
 		}
-		registry = writerProviders.Registry
-	} else {
-		registry = transporterProviders.Registry
+		app.Consumers = append(app.Consumers, consumer)
+
+		logger.Info("Bootstrap completed",
+			"cluster", subscriberCfg.Cluster,
+			"dlq_cluster", subscriberCfg.DLQCluster,
+			"brokers", appConfigs.Envs.Pubsub.DeliveryBrokersHosts,
+			"mode_topic", subscriberCfg.Topic,
+			"handlers", subscriberCfg.Handlers,
+		)
 	}
 
-	eventBus := pkgEvents.NewEventBus(pkgEvents.EventBusDependencies{
-		EventHandlerRegistry: registry,
-	})
+	app.pauseOnOpenCircuits()
+	metrics.MustRegister(&consumerLagCollector{consumers: app.Consumers})
 
-	subscriber, err := createKafkaSubscriber(appConfigs, logger)
-	if err != nil {
-		return nil, fmt.Errorf("create kafka subscriber: %w", err)
+	readiness, liveness := buildHealthCheckers(app)
+	app.AdminServer = newAdminServer(appConfigs.Envs.Admin.HTTPAddr, appConfigs.Envs.Admin.Token, readiness, liveness, app.Consumers)
+
+	return app, nil
+}
+
+func createConsumer(app *Application, subscriberCfg *subscriber.SubscriberConfig) (*Consumer, error) {
+	if subscriberCfg.Handlers == "" {
+		subscriberCfg.Handlers = subscriber.HandlersTransporters
+		if subscriberCfg.Topic == internalCommandsTopic {
+			subscriberCfg.Handlers = subscriber.HandlersWriters
+		}
+	}
+
+	var registry *pkgEvents.EventHandlerRegistry
+
+	switch subscriberCfg.Handlers {
+	case subscriber.HandlersWriters:
+		if app.WriterProviders == nil {
+			writerProviders, err := providers.NewWriterProviders(app.Configs.Envs, app.ServiceProviders)
+			if err != nil {
+				return nil, fmt.Errorf("create writer providers: %w", err)
+			}
+			app.WriterProviders = writerProviders
+		}
+		registry = app.WriterProviders.Registry
+	default:
+		transporterProviders, err := providers.NewTransporterProviders(app.Configs.Envs, subscriberCfg, app.ServiceProviders)
+		if err != nil {
+			return nil, fmt.Errorf("create transporter providers: %w", err)
+		}
+		registry = transporterProviders.Registry
 	}
 
-	logger.Info("Bootstrap completed",
-		"cluster", appConfigs.SubscriberConfigs.Cluster,
-		"dlq_cluster", appConfigs.SubscriberConfigs.DLQCluster,
-		"brokers", appConfigs.Envs.Pubsub.DeliveryBrokersHosts,
-		"mode_topic", appConfigs.SubscriberConfigs.Topic,
+	consumerLogger := app.Logger.With(
+		"topic", subscriberCfg.Topic,
+		"consumer_group", subscriberCfg.ConsumerGroup,
 	)
 
-	return &Application{
-		Configs:              appConfigs,
-		Logger:               logger,
-		ServiceProviders:     serviceProviders,
-		TransporterProviders: transporterProviders,
-		WriterProviders:      writerProviders,
-		EventBus:             eventBus,
-		Registry:             registry,
-		Subscriber:           subscriber,
+	var (
+		messageSubscriber watermillMessage.Subscriber = app.ServiceProviders.MemoryPubSub
+		monitor           *appWatermill.ConsumerGroupMonitor
+	)
+	if app.ServiceProviders.MemoryPubSub == nil {
+		monitor = appWatermill.NewConsumerGroupMonitor(subscriberCfg.ConsumerGroup, consumerLogger)
+		kafkaSubscriber, err := createKafkaSubscriber(app.Configs.Envs.Pubsub.DeliveryBrokersHosts, subscriberCfg, monitor, consumerLogger)
+		if err != nil {
+			return nil, fmt.Errorf("create kafka subscriber: %w", err)
+		}
+		messageSubscriber = kafkaSubscriber
+	}
+
+	var middlewares []pkgEvents.Middleware
+	if len(subscriberCfg.AllowedSources) > 0 {
+		middlewares = append(middlewares, pkgEvents.AllowSources(subscriberCfg.AllowedSources...))
+	}
+	// Handlers publish through the outbox when it is enabled, so their writes and messages share a transaction.
+	if app.Configs.Envs.Outbox.Enabled && app.ServiceProviders.TransactionManager != nil {
+		middlewares = append(middlewares, pkgEvents.Transactional(app.ServiceProviders.TransactionManager.WithTransaction))
+	}
+
+	return &Consumer{
+		Config: subscriberCfg,
+		Logger: consumerLogger,
+		EventBus: pkgEvents.NewEventBus(pkgEvents.EventBusDependencies{
+			EventHandlerRegistry:  registry,
+			ProcessedMessageStore: app.ServiceProviders.ProcessedMessageStore,
+			ProcessedMessageScope: subscriberCfg.ConsumerGroup,
+			Codecs:                app.ServiceProviders.Codecs,
+			Middlewares:           middlewares,
+			Topic:                 subscriberCfg.Topic,
+			ConsumerGroup:         subscriberCfg.ConsumerGroup,
+			UnknownEventTypes:     pkgEvents.UnknownEventTypePolicy(subscriberCfg.UnknownEventTypes),
+			Decoding:              pkgEvents.DecodingMode(subscriberCfg.Decoding),
+		}),
+		Registry:        registry,
+		Subscriber:      messageSubscriber,
+		DLQPublisher:    app.ServiceProviders.MessagePublisher,
+		DLQTopic:        app.Configs.Envs.Pubsub.DLQTopic,
+		Monitor:         monitor,
+		CircuitBreakers: consumerCircuitBreakers(app, subscriberCfg),
+		flow:            newFlowControl(),
 	}, nil
 }
 
-func createKafkaSubscriber(appConfigs *config.AppConfigs, logger appLogger.Logger) (*watermillKafka.Subscriber, error) {
-	saramaConfig := watermillKafka.DefaultSaramaSubscriberConfig()
-	saramaConfig.ClientID = appConfigs.SubscriberConfigs.ConsumerName
-	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
-
-	return watermillKafka.NewSubscriber(
-		watermillKafka.SubscriberConfig{
-			Brokers:               appConfigs.Envs.Pubsub.DeliveryBrokersHosts,
-			ConsumerGroup:         appConfigs.SubscriberConfigs.ConsumerGroup,
-			OverwriteSaramaConfig: saramaConfig,
-			Unmarshaler:           watermillKafka.DefaultMarshaler{},
-		},
-		appWatermill.NewWatermillLoggerFromLogger(logger),
+// runApplication runs every consumer until all of them stop. A consumer that fails is logged
+// and does not stop the others.
+func runApplication(ctx context.Context, app *Application) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
 	)
-}
 
-func runApplication(ctx context.Context, app *Application) error {
-	messages, err := app.Subscriber.Subscribe(ctx, app.Configs.SubscriberConfigs.Topic)
-	if err != nil {
-		return fmt.Errorf("subscribe to topic %s: %w", app.Configs.SubscriberConfigs.Topic, err)
+	for _, consumer := range app.Consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := consumer.Run(ctx); err != nil {
+				consumer.Logger.Error("Kafka consumer stopped with error", "error", err.Error())
+
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("consumer for topic %s: %w", consumer.Config.Topic, err))
+				mu.Unlock()
+				return
+			}
+
+			consumer.Logger.Info("Kafka consumer stopped")
+		}()
 	}
 
-	app.Logger.Info("Kafka consumer started",
-		"topic", app.Configs.SubscriberConfigs.Topic,
-		"consumer_group", app.Configs.SubscriberConfigs.ConsumerGroup,
-	)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// handleControlSignals pauses every consumer on SIGUSR1 and resumes them on SIGUSR2.
+func (a *Application) handleControlSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
 
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
-		case msg, ok := <-messages:
-			if !ok {
-				return nil
+			return
+		case sig := <-signals:
+			for _, consumer := range a.Consumers {
+				if sig == syscall.SIGUSR1 {
+					consumer.Pause(pauseReasonOperator)
+				} else {
+					consumer.Resume(pauseReasonOperator)
+				}
 			}
+		}
+	}
+}
 
-			if err := processMessage(ctx, app, msg); err != nil {
-				app.Logger.Error("Failed to process Kafka message",
-					"error", err.Error(),
-					"message_uuid", msg.UUID,
-				)
-				msg.Nack()
-				continue
+// pauseOnOpenCircuits pauses the consumers that depend on a circuit breaker while it is open, so messages wait
+// on the broker instead of being retried into the DLQ. A breaker that probes its dependency keeps them paused
+// until it closes; one that does not resumes them when it goes half-open, letting the next messages probe.
+func (a *Application) pauseOnOpenCircuits() {
+	for _, breaker := range a.ServiceProviders.CircuitBreakers() {
+		breaker.OnStateChange(func(name string, _, to circuitbreaker.State) {
+			reason := "circuit_breaker:" + name
+			for _, consumer := range a.Consumers {
+				if !consumer.DependsOn(name) {
+					continue
+				}
+				switch {
+				case to == circuitbreaker.StateOpen:
+					consumer.Pause(reason)
+				case to == circuitbreaker.StateClosed || !breaker.Probes():
+					consumer.Resume(reason)
+				}
 			}
+		})
+	}
+}
 
-			msg.Ack()
-		}
+// consumerCircuitBreakers names the circuit breakers of the dependencies a subscription calls. Every
+// subscription checks the processed message store, which lives in MongoDB, and only transporters publish,
+// unless the outbox turns their messages into MongoDB writes.
+func consumerCircuitBreakers(app *Application, subscriberCfg *subscriber.SubscriberConfig) []string {
+	var names []string
+	if breaker := app.ServiceProviders.MongoBreaker; breaker != nil {
+		names = append(names, breaker.Name())
+	}
+	if breaker := app.ServiceProviders.PublisherBreaker; breaker != nil &&
+		subscriberCfg.Handlers == subscriber.HandlersTransporters && !app.Configs.Envs.Outbox.Enabled {
+		names = append(names, breaker.Name())
 	}
+	return names
 }
 
-func shutdownApplication(ctx context.Context, app *Application) error {
-	if app != nil && app.Subscriber != nil {
-		if err := app.Subscriber.Close(); err != nil {
-			return fmt.Errorf("close subscriber: %w", err)
-		}
+// drainConsumers stops fetching and waits for the in-flight messages of every consumer, so no message is
+// being handled when the subscribers are closed.
+func drainConsumers(ctx context.Context, app *Application) {
+	for _, consumer := range app.Consumers {
+		consumer.Drain()
 	}
 
-	if app != nil && app.WriterProviders != nil {
-		if err := app.WriterProviders.Close(ctx); err != nil {
-			return fmt.Errorf("close writer providers: %w", err)
+	for _, consumer := range app.Consumers {
+		select {
+		case <-consumer.Stopped():
+			continue
+		case <-ctx.Done():
+		}
+
+		consumer.Logger.Warn("Shutdown grace period elapsed before the consumer drained", "state", consumer.State())
+		for _, message := range consumer.InFlight() {
+			consumer.Logger.Warn("Abandoning in-flight message, it will be redelivered",
+				"message_uuid", message.UUID,
+				"event_type", message.EventType,
+				"message_key", message.Key,
+				"running_for", time.Since(message.StartedAt).String(),
+			)
 		}
 	}
+}
 
-	if app == nil || app.ServiceProviders == nil || app.ServiceProviders.MessagePublisher == nil {
+// shutdownApplication stops fetching, waits up to the grace period for in-flight handlers, and then closes
+// the subscribers, flushes the outbox and publisher and disconnects MongoDB, in that order.
+func shutdownApplication(app *Application) error {
+	if app == nil {
 		return nil
 	}
 
-	if err := app.ServiceProviders.MessagePublisher.Close(ctx); err != nil {
-		return fmt.Errorf("close message publisher: %w", err)
+	envs := app.Configs.Envs
+	app.Logger.Info("Shutting down", "grace_period", envs.Shutdown.GracePeriod.String())
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), envs.Shutdown.GracePeriod)
+	drainConsumers(drainCtx, app)
+	cancelDrain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), envs.Shutdown.CloseTimeout)
+	defer cancel()
+
+	var errs []error
+
+	for _, consumer := range app.Consumers {
+		if err := consumer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close subscriber for topic %s: %w", consumer.Config.Topic, err))
+		}
+	}
+
+	if err := app.ServiceProviders.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("close service providers: %w", err))
+	}
+
+	if app.AdminServer != nil {
+		if err := app.AdminServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown admin server: %w", err))
+		}
+	}
+
+	if app.ShutdownTracing != nil {
+		if err := app.ShutdownTracing(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown tracing: %w", err))
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
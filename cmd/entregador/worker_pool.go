@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+// workerPool processes messages concurrently while keeping messages that share a key on the same worker,
+// so they are handled in the order they were received.
+//
+// Watermill's Kafka subscriber only delivers the next message of a partition after the previous one was
+// acked or nacked, so there is at most one in-flight message per partition and offsets are never committed
+// past a message that is still being processed.
+//
+// Workers are unbuffered, so Dispatch blocks while the worker owning a key is busy, and with it the read
+// loop: a slow key delays the keys sharing its worker and the other partitions of the subscription.
+type workerPool struct {
+	workers []chan *watermillMessage.Message
+	wg      sync.WaitGroup
+}
+
+func newWorkerPool(size int, handle func(msg *watermillMessage.Message)) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &workerPool{workers: make([]chan *watermillMessage.Message, size)}
+	for index := range pool.workers {
+		worker := make(chan *watermillMessage.Message)
+		pool.workers[index] = worker
+
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			for msg := range worker {
+				handle(msg)
+			}
+		}()
+	}
+
+	return pool
+}
+
+// Dispatch blocks until the worker owning key accepts the message or ctx is cancelled.
+func (p *workerPool) Dispatch(ctx context.Context, key string, msg *watermillMessage.Message) bool {
+	select {
+	case p.workers[p.workerIndex(key)] <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stop stops accepting messages and waits for the in-flight ones to finish.
+func (p *workerPool) Stop() {
+	for _, worker := range p.workers {
+		close(worker)
+	}
+	p.wg.Wait()
+}
+
+func (p *workerPool) workerIndex(key string) int {
+	if len(p.workers) == 1 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(p.workers)))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	watermillMessage "github.com/ThreeDotsLabs/watermill/message"
+)
+
+func TestWorkerPoolKeepsKeyOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		keys        []string
+		perKey      int
+		wantWorkers int
+	}{
+		{name: "size below one uses a single worker", size: 0, keys: []string{"a", "b"}, perKey: 5, wantWorkers: 1},
+		{name: "single worker", size: 1, keys: []string{"a", "b", "c"}, perKey: 5, wantWorkers: 1},
+		{name: "several workers", size: 4, keys: []string{"a", "b", "c", "d", "e", "f", "g", "h"}, perKey: 20, wantWorkers: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			handled := make(map[string][]string)
+
+			pool := newWorkerPool(tt.size, func(msg *watermillMessage.Message) {
+				// Yield so messages of different keys interleave across workers.
+				time.Sleep(time.Microsecond)
+
+				mu.Lock()
+				defer mu.Unlock()
+				key := msg.Metadata.Get("key")
+				handled[key] = append(handled[key], msg.UUID)
+			})
+			if len(pool.workers) != tt.wantWorkers {
+				t.Fatalf("workers = %d, want %d", len(pool.workers), tt.wantWorkers)
+			}
+
+			want := make(map[string][]string)
+			for i := range tt.perKey {
+				for _, key := range tt.keys {
+					msg := watermillMessage.NewMessage(fmt.Sprintf("%s-%d", key, i), nil)
+					msg.Metadata.Set("key", key)
+					want[key] = append(want[key], msg.UUID)
+
+					if !pool.Dispatch(context.Background(), key, msg) {
+						t.Fatalf("Dispatch(%s) = false, want true", msg.UUID)
+					}
+				}
+			}
+			pool.Stop()
+
+			if !reflect.DeepEqual(handled, want) {
+				t.Errorf("handled = %v, want %v", handled, want)
+			}
+			for _, key := range tt.keys {
+				if index := pool.workerIndex(key); index >= tt.wantWorkers {
+					t.Errorf("workerIndex(%q) = %d, want an index below %d", key, index, tt.wantWorkers)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerPoolDispatchStopsWhenContextIsCancelled(t *testing.T) {
+	release := make(chan struct{})
+	pool := newWorkerPool(1, func(*watermillMessage.Message) { <-release })
+	defer pool.Stop()
+	defer close(release)
+
+	if !pool.Dispatch(context.Background(), "key", watermillMessage.NewMessage("busy", nil)) {
+		t.Fatal("Dispatch() = false, want the idle worker to accept the message")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if pool.Dispatch(ctx, "key", watermillMessage.NewMessage("waiting", nil)) {
+		t.Error("Dispatch() = true, want false while the worker is busy and ctx is done")
+	}
+}
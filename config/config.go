@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/config/subscriber"
 	"github.com/joeshaw/envdecode"
@@ -20,6 +21,18 @@ const (
 	DeliveryClusterName = "Delivery"
 )
 
+const (
+	PubsubDriverKafka  = "kafka"
+	PubsubDriverMemory = "memory"
+)
+
+const (
+	StorageDriverMongoDB = "mongodb"
+	StorageDriverMemory  = "memory"
+)
+
+const SchemaRegistryMemory = "memory"
+
 var AppName = "delivery-subscriber"
 var Envs *Environment
 
@@ -30,16 +43,71 @@ type Environment struct {
 		Name     string `env:"APP_NAME,default=delivery-subscriber"`
 		Version  string `env:"APP_VERSION,default=1.0.0"`
 	}
+	Storage struct {
+		// Driver memory keeps every store in process, for tests and local runs without MongoDB.
+		Driver string `env:"STORAGE_DRIVER,default=mongodb"`
+	}
 	MongoDB struct {
-		URI      string `env:"MONGODB_URI"`
-		Database string `env:"MONGODB_DATABASE"`
+		URI                  string        `env:"MONGODB_URI"`
+		Database             string        `env:"MONGODB_DATABASE"`
+		ProcessedMessagesTTL time.Duration `env:"MONGODB_PROCESSED_MESSAGES_TTL,default=168h"`
 	}
 	Pubsub struct {
+		Driver                  string `env:"PUBSUB_DRIVER,default=kafka"`
 		DeliveryBrokersHostsRaw string `env:"DELIVERY_BROKER_HOSTS"`
 		DeliveryBrokersHosts    []string
 		DLQTopic                string `env:"DLQ_TOPIC,default=delivery-subscriber.dlq"`
 		BrokerHosts             []string
 	}
+	SchemaRegistry struct {
+		// URL is empty when no registry is used, or "memory" for an in-process stand-in.
+		URL      string        `env:"SCHEMA_REGISTRY_URL"`
+		Username string        `env:"SCHEMA_REGISTRY_USERNAME"`
+		Password string        `env:"SCHEMA_REGISTRY_PASSWORD"`
+		Timeout  time.Duration `env:"SCHEMA_REGISTRY_TIMEOUT,default=5s"`
+		// SchemasDir holds the schemas registered at startup, one file per subject.
+		SchemasDir string `env:"SCHEMA_REGISTRY_SCHEMAS_DIR"`
+	}
+	Outbox struct {
+		Enabled      bool          `env:"OUTBOX_ENABLED,default=false"`
+		PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL,default=1s"`
+		BatchSize    int           `env:"OUTBOX_BATCH_SIZE,default=100"`
+		Retention    time.Duration `env:"OUTBOX_RETENTION,default=168h"`
+		// MaxAttempts parks a message after that many failed publishes; its key stays held until it is dealt with.
+		MaxAttempts   int           `env:"OUTBOX_MAX_ATTEMPTS,default=10"`
+		LeaseDuration time.Duration `env:"OUTBOX_LEASE_DURATION,default=30s"`
+	}
+	Admin struct {
+		// HTTPAddr listens on loopback by default, since /metrics, /readyz and /consumers are not
+		// authenticated. Set it to ":8080" where probes or scrapers reach the pod over the network.
+		HTTPAddr string `env:"ADMIN_HTTP_ADDR,default=127.0.0.1:8080"`
+		// Token is required as a bearer token by the consumer control endpoints; without it they only
+		// accept requests from localhost.
+		Token string `env:"ADMIN_TOKEN"`
+	}
+	Shutdown struct {
+		// GracePeriod bounds how long in-flight messages may run after a shutdown signal.
+		GracePeriod  time.Duration `env:"SHUTDOWN_GRACE_PERIOD,default=30s"`
+		CloseTimeout time.Duration `env:"SHUTDOWN_CLOSE_TIMEOUT,default=10s"`
+	}
+	CircuitBreaker struct {
+		Enabled             bool          `env:"CIRCUIT_BREAKER_ENABLED,default=true"`
+		FailureThreshold    int           `env:"CIRCUIT_BREAKER_FAILURE_THRESHOLD,default=5"`
+		OpenTimeout         time.Duration `env:"CIRCUIT_BREAKER_OPEN_TIMEOUT,default=30s"`
+		HalfOpenMaxRequests int           `env:"CIRCUIT_BREAKER_HALF_OPEN_MAX_REQUESTS,default=1"`
+	}
+	Health struct {
+		CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT,default=3s"`
+		// StallTimeout must exceed the longest a message can spend in retries.
+		StallTimeout time.Duration `env:"HEALTH_STALL_TIMEOUT,default=10m"`
+	}
+	Tracing struct {
+		Exporter     string  `env:"TRACING_EXPORTER,default=none"`
+		FilePath     string  `env:"TRACING_FILE_PATH"`
+		OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT"`
+		OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE,default=false"`
+		SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO,default=1"`
+	}
 	Delivery struct {
 		URL string `env:"DELIVERY_URL,required"`
 	}
@@ -47,7 +115,7 @@ type Environment struct {
 
 type AppConfigs struct {
 	Envs              *Environment
-	SubscriberConfigs *subscriber.SubscriberConfig
+	SubscriberConfigs *subscriber.Config
 }
 
 func NewConfig() (*AppConfigs, error) {
@@ -77,6 +145,12 @@ func ReadEnvs() (*Environment, error) {
 		if err := envdecode.Decode(Envs); err != nil {
 			return nil, fmt.Errorf("error loading environment variables: %w", err)
 		}
+		if Envs.Pubsub.Driver != PubsubDriverKafka && Envs.Pubsub.Driver != PubsubDriverMemory {
+			return nil, fmt.Errorf("invalid PUBSUB_DRIVER %q: expected %s or %s", Envs.Pubsub.Driver, PubsubDriverKafka, PubsubDriverMemory)
+		}
+		if Envs.Storage.Driver != StorageDriverMongoDB && Envs.Storage.Driver != StorageDriverMemory {
+			return nil, fmt.Errorf("invalid STORAGE_DRIVER %q: expected %s or %s", Envs.Storage.Driver, StorageDriverMongoDB, StorageDriverMemory)
+		}
 		Envs.Pubsub.DeliveryBrokersHosts = strings.Split(Envs.Pubsub.DeliveryBrokersHostsRaw, ",")
 		AppName = Envs.App.Name
 	}
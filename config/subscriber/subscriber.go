@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/pkg/duration"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/retry"
 	validator "github.com/go-playground/validator/v10"
 )
 
@@ -19,13 +20,55 @@ const (
 	defaultRetryInitialInterval duration.Duration = duration.Duration(time.Second)
 	defaultRetryMaxInterval     duration.Duration = duration.Duration(time.Second * 30)
 	defaultRetryMultiplier      float64           = 2.0
+	defaultConcurrency          int               = 1
 )
 
 type RetryConfig struct {
 	MaxRetries      int               `json:"max_retries"`
 	InitialInterval duration.Duration `json:"initial_interval"`
 	MaxInterval     duration.Duration `json:"max_interval"`
-	Multiplier      float64           `json:"multiplier"`
+	Multiplier      float64           `json:"multiplier" validate:"omitempty,gte=1"`
+}
+
+func defaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:      defaultMaxRetries,
+		InitialInterval: defaultRetryInitialInterval,
+		MaxInterval:     defaultRetryMaxInterval,
+		Multiplier:      defaultRetryMultiplier,
+	}
+}
+
+// UnmarshalJSON starts from the defaults, so fields missing from the retry block keep them while an
+// explicit "max_retries": 0 still disables retries.
+func (r *RetryConfig) UnmarshalJSON(data []byte) error {
+	type plainRetryConfig RetryConfig
+	retryCfg := plainRetryConfig(defaultRetryConfig())
+	if err := json.Unmarshal(data, &retryCfg); err != nil {
+		return err
+	}
+	*r = RetryConfig(retryCfg)
+	return nil
+}
+
+func (r *RetryConfig) Policy() retry.Policy {
+	return retry.Policy{
+		MaxRetries:      r.MaxRetries,
+		InitialInterval: r.InitialInterval.Duration(),
+		MaxInterval:     r.MaxInterval.Duration(),
+		Multiplier:      r.Multiplier,
+	}
+}
+
+const (
+	HandlersTransporters = "transporters"
+	HandlersWriters      = "writers"
+)
+
+// Config lists every subscription run by a single process.
+type Config struct {
+	App           string              `json:"app" validate:"required"`
+	Subscriptions []*SubscriberConfig `json:"subscriptions" validate:"required,min=1"`
 }
 
 type SubscriberConfig struct {
@@ -33,10 +76,64 @@ type SubscriberConfig struct {
 	ConsumerGroup string            `json:"consumer_group" validate:"required"`
 	ConsumerName  string            `json:"consumer_name" validate:"required"`
 	Topic         string            `json:"topic" validate:"required"`
+	Handlers      string            `json:"handlers" validate:"omitempty,oneof=transporters writers"`
 	TimeOut       duration.Duration `json:"timeout"`
 	Cluster       string            `json:"cluster"`
 	DLQCluster    string            `json:"dlq_cluster"`
 	RetryConfig   *RetryConfig      `json:"retry"`
+	// Concurrency is the number of workers, and so the most messages handled at once. Keys are hashed to
+	// workers, which take one message at a time: a slow key holds up every key sharing its worker and, once
+	// the read loop waits on that worker, every other partition of the subscription.
+	Concurrency int `json:"concurrency" validate:"gte=0"`
+	// AllowedSources, when set, rejects messages whose Source header is not listed.
+	AllowedSources []string `json:"allowed_sources"`
+	// UnknownEventTypes is ignore (default), warn or reject; Decoding is lenient (default) or strict, which
+	// rejects payloads with fields unknown to the handler.
+	UnknownEventTypes string `json:"unknown_event_types" validate:"omitempty,oneof=ignore warn reject"`
+	Decoding          string `json:"decoding" validate:"omitempty,oneof=lenient strict"`
+}
+
+func initializeConfig(dat []byte) (*Config, error) {
+	var cfg *Config
+	if err := json.Unmarshal(dat, &cfg); err != nil {
+		return nil, err
+	}
+
+	// Files without a subscriptions list describe a single subscription.
+	if cfg == nil || len(cfg.Subscriptions) == 0 {
+		subscriberCfg, err := initializeSubscriberConfig(dat)
+		if err != nil {
+			return nil, err
+		}
+		return &Config{App: subscriberCfg.App, Subscriptions: []*SubscriberConfig{subscriberCfg}}, nil
+	}
+
+	if err := validator.New().Struct(cfg); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(cfg.Subscriptions))
+	for index, subscriberCfg := range cfg.Subscriptions {
+		if subscriberCfg == nil {
+			return nil, fmt.Errorf("subscription %d cannot be empty", index)
+		}
+
+		if subscriberCfg.App == "" {
+			subscriberCfg.App = cfg.App
+		}
+
+		if err := setSubscriberDefaults(subscriberCfg); err != nil {
+			return nil, fmt.Errorf("subscription %d: %w", index, err)
+		}
+
+		subscriptionID := subscriberCfg.Topic + "/" + subscriberCfg.ConsumerGroup
+		if seen[subscriptionID] {
+			return nil, fmt.Errorf("subscription %d: topic %s is already consumed by group %s", index, subscriberCfg.Topic, subscriberCfg.ConsumerGroup)
+		}
+		seen[subscriptionID] = true
+	}
+
+	return cfg, nil
 }
 
 func initializeSubscriberConfig(dat []byte) (*SubscriberConfig, error) {
@@ -45,10 +142,18 @@ func initializeSubscriberConfig(dat []byte) (*SubscriberConfig, error) {
 		return nil, err
 	}
 
-	if err := validator.New().Struct(subscriberCfg); err != nil {
+	if err := setSubscriberDefaults(subscriberCfg); err != nil {
 		return nil, err
 	}
 
+	return subscriberCfg, nil
+}
+
+func setSubscriberDefaults(subscriberCfg *SubscriberConfig) error {
+	if err := validator.New().Struct(subscriberCfg); err != nil {
+		return err
+	}
+
 	if subscriberCfg.TimeOut == 0 {
 		subscriberCfg.TimeOut = defaultTimeOut
 	}
@@ -61,19 +166,35 @@ func initializeSubscriberConfig(dat []byte) (*SubscriberConfig, error) {
 		subscriberCfg.DLQCluster = defaultDlqCluster
 	}
 
+	if subscriberCfg.Concurrency == 0 {
+		subscriberCfg.Concurrency = defaultConcurrency
+	}
+
 	if subscriberCfg.RetryConfig == nil {
-		subscriberCfg.RetryConfig = &RetryConfig{
-			MaxRetries:      defaultMaxRetries,
-			InitialInterval: defaultRetryInitialInterval,
-			MaxInterval:     defaultRetryMaxInterval,
-			Multiplier:      defaultRetryMultiplier,
-		}
+		retryCfg := defaultRetryConfig()
+		subscriberCfg.RetryConfig = &retryCfg
 	}
 
-	return subscriberCfg, nil
+	if subscriberCfg.RetryConfig.MaxRetries < 0 {
+		return fmt.Errorf("retry max_retries cannot be negative")
+	}
+
+	if subscriberCfg.RetryConfig.InitialInterval <= 0 {
+		subscriberCfg.RetryConfig.InitialInterval = defaultRetryInitialInterval
+	}
+
+	if subscriberCfg.RetryConfig.MaxInterval <= 0 {
+		subscriberCfg.RetryConfig.MaxInterval = defaultRetryMaxInterval
+	}
+
+	if subscriberCfg.RetryConfig.Multiplier == 0 {
+		subscriberCfg.RetryConfig.Multiplier = defaultRetryMultiplier
+	}
+
+	return nil
 }
 
-func Read() (*SubscriberConfig, error) {
+func Read() (*Config, error) {
 	configPath := flag.String("config", "", "path to config file")
 	flag.Parse()
 	if *configPath == "" {
@@ -85,7 +206,7 @@ func Read() (*SubscriberConfig, error) {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
 
-	cfg, err := initializeSubscriberConfig(f)
+	cfg, err := initializeConfig(f)
 	if err != nil {
 		return nil, fmt.Errorf("cannot initialize config file: %w", err)
 	}
@@ -0,0 +1,89 @@
+package subscriber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/duration"
+)
+
+func TestInitializeConfigRetryDefaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry string
+		want  RetryConfig
+	}{
+		{name: "retry is missing", retry: ``, want: defaultRetryConfig()},
+		{name: "empty retry block", retry: `,"retry":{}`, want: defaultRetryConfig()},
+		{
+			name:  "only the multiplier",
+			retry: `,"retry":{"multiplier":1.5}`,
+			want: RetryConfig{
+				MaxRetries:      defaultMaxRetries,
+				InitialInterval: defaultRetryInitialInterval,
+				MaxInterval:     defaultRetryMaxInterval,
+				Multiplier:      1.5,
+			},
+		},
+		{
+			name:  "retries disabled",
+			retry: `,"retry":{"max_retries":0,"max_interval":"10s"}`,
+			want: RetryConfig{
+				InitialInterval: defaultRetryInitialInterval,
+				MaxInterval:     duration.Duration(10 * time.Second),
+				Multiplier:      defaultRetryMultiplier,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dat := []byte(`{"app":"app","consumer_group":"group","consumer_name":"consumer","topic":"topic"` + tt.retry + `}`)
+
+			cfg, err := initializeConfig(dat)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := *cfg.Subscriptions[0].RetryConfig; got != tt.want {
+				t.Errorf("retry = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitializeConfigRetryMultiplier(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   string
+		want    float64
+		wantErr bool
+	}{
+		{name: "default when retry is missing", retry: ``, want: defaultRetryMultiplier},
+		{name: "default when multiplier is missing", retry: `,"retry":{"max_retries":3}`, want: defaultRetryMultiplier},
+		{name: "configured multiplier", retry: `,"retry":{"multiplier":1.5}`, want: 1.5},
+		{name: "constant backoff", retry: `,"retry":{"multiplier":1}`, want: 1},
+		{name: "multiplier below one", retry: `,"retry":{"multiplier":0.5}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dat := []byte(`{"app":"app","consumer_group":"group","consumer_name":"consumer","topic":"topic"` + tt.retry + `}`)
+
+			cfg, err := initializeConfig(dat)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := cfg.Subscriptions[0].RetryConfig.Multiplier; got != tt.want {
+				t.Errorf("multiplier = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,8 +5,8 @@ const (
 )
 
 type ProcessCreateResidentCommand struct {
-	CommandID string `json:"command_id"`
-	Name      string `json:"name"`
-	Apartment string `json:"apartment"`
+	CommandID string `json:"command_id" validate:"required"`
+	Name      string `json:"name" validate:"required"`
+	Apartment string `json:"apartment" validate:"required"`
 	Phone     string `json:"phone"`
 }
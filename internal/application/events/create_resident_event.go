@@ -5,7 +5,7 @@ const (
 )
 
 type CreateResident struct {
-	Name      string `json:"name"`
-	Apartment string `json:"apartment"`
+	Name      string `json:"name" validate:"required"`
+	Apartment string `json:"apartment" validate:"required"`
 	Phone     string `json:"phone"`
 }
@@ -0,0 +1,20 @@
+package transporters
+
+import (
+	"context"
+
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	"github.com/google/uuid"
+)
+
+var commandIDNamespace = uuid.MustParse("6f1d8e52-3c4b-4d7e-9a51-2b8f0c7d4e19")
+
+// buildCommandID derives the command ID from the source message ID, so a redelivered event always
+// produces the same command. Outside of a message context a random ID is returned.
+func buildCommandID(ctx context.Context, commandType string) string {
+	sourceMessageID := pkgEvents.MessageIDFromContext(ctx)
+	if sourceMessageID == "" {
+		return uuid.New().String()
+	}
+	return uuid.NewSHA1(commandIDNamespace, []byte(commandType+":"+sourceMessageID)).String()
+}
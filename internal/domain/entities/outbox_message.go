@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	// OutboxStatusParked marks messages that kept failing and are no longer relayed.
+	OutboxStatusParked = "parked"
+)
+
+type OutboxMessage struct {
+	ID string
+	// Sequence is assigned by the repository on Add and increases across processes, so it gives the
+	// order in which messages are relayed.
+	Sequence      int64
+	Topic         string
+	Headers       pubsub.Headers
+	Payload       []byte
+	Status        string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        time.Time
+}
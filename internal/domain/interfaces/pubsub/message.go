@@ -2,22 +2,79 @@ package pubsub
 
 import (
 	"context"
+	"time"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/config"
 )
 
 const (
+	MessageIDHeader     = "MessageID"
+	CorrelationIDHeader = "CorrelationID"
+	CausationIDHeader   = "CausationID"
 	EventTypeHeader     = "EventType"
+	EventVersionHeader  = "EventVersion"
 	KeyHeader           = "Key"
 	SourceHeader        = "Source"
 	OriginalTopicHeader = "OriginalTopic"
+	ReplayCountHeader   = "ReplayCount"
+	ContentTypeHeader   = "ContentType"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+const (
+	OriginalPartitionHeader = "OriginalPartition"
+	OriginalOffsetHeader    = "OriginalOffset"
+	ConsumerGroupHeader     = "ConsumerGroup"
+	FailureReasonHeader     = "FailureReason"
+	AttemptCountHeader      = "AttemptCount"
+	ErrorStackHeader        = "ErrorStack"
+	FailedAtHeader          = "FailedAt"
+)
+
+const (
+	FailureReasonRetriesExhausted     = "retries_exhausted"
+	FailureReasonUnconvertiblePayload = "unconvertible_payload"
+	FailureReasonMissingEventType     = "missing_event_type"
+	FailureReasonPermanentError       = "permanent_error"
+	FailureReasonPanic                = "panic"
+	FailureReasonInvalidPayload       = "invalid_payload"
+	FailureReasonUnknownEventType     = "unknown_event_type"
 )
 
 type Headers struct {
+	MessageID string
+	// CorrelationID is shared by every message of the same flow; CausationID is the ID of the message
+	// whose handling produced this one.
+	CorrelationID string
+	CausationID   string
 	EventType     string
-	Key           string
-	Source        string
+	// EventVersion is the payload schema version of EventType; 0 means unversioned.
+	EventVersion int
+	Key          string
+	Source       string
+	// ContentType selects the payload codec; empty means JSON.
+	ContentType   string
 	OriginalTopic *string
+	ReplayCount   int
+	DeadLetter    *DeadLetter
+	// TraceContext carries the W3C trace context (traceparent, tracestate) of the producing span.
+	TraceContext map[string]string
+}
+
+// DeadLetter describes why and where a message failed before being sent to the DLQ.
+type DeadLetter struct {
+	OriginalPartition int32
+	OriginalOffset    int64
+	ConsumerGroup     string
+	FailureReason     string
+	AttemptCount      int
+	Errors            []string
+	FailedAt          time.Time
 }
 
 type Payload[T any] struct {
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// LeaseRepositoryPort grants named leases to a single owner at a time, so work such as relaying the outbox
+// runs in only one replica.
+type LeaseRepositoryPort interface {
+	// Acquire takes or renews the lease for ttl. It returns false while another owner holds it.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+)
+
+type OutboxRepositoryPort interface {
+	Add(ctx context.Context, messages ...*entities.OutboxMessage) error
+	// FetchPending returns pending messages with a sequence above afterSequence, in sequence order.
+	FetchPending(ctx context.Context, afterSequence int64, limit int) ([]*entities.OutboxMessage, error)
+	// ParkedKeys returns the message keys that have a parked message.
+	ParkedKeys(ctx context.Context) ([]string, error)
+	CountPending(ctx context.Context) (int64, error)
+	MarkSent(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error
+	MarkParked(ctx context.Context, id string, cause error) error
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+import "context"
+
+// TransactionManagerPort runs fn inside a transaction. Repositories called with the context passed to fn
+// take part in the same transaction.
+type TransactionManagerPort interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
+	"github.com/google/uuid"
+)
+
+// Publisher stores messages in the outbox instead of sending them. Publishing with a context obtained from
+// TransactionManagerPort.WithTransaction, as handlers wrapped by events.Transactional do, commits the
+// messages together with the handler's domain writes.
+// Payloads that are not JSON are stored already encoded, so the relay does not need their Go types.
+type Publisher struct {
+	repository interfaces.OutboxRepositoryPort
+	codecs     *codec.Registry
+}
+
+func NewPublisher(repository interfaces.OutboxRepositoryPort, codecs *codec.Registry) pubsub.MessagePublisher[any] {
+	return &Publisher{repository: repository, codecs: codecs}
+}
+
+func (p *Publisher) Publish(ctx context.Context, topic string, messages ...*pubsub.Message[any]) error {
+	outboxMessages := make([]*entities.OutboxMessage, 0, len(messages))
+	for _, message := range messages {
+		payload, err := p.encode(ctx, topic, message)
+		if err != nil {
+			return fmt.Errorf("marshal outbox payload: %w", err)
+		}
+
+		headers := message.Headers
+		if headers.MessageID == "" {
+			headers.MessageID = uuid.New().String()
+		}
+		events.PropagateHeaders(ctx, &headers)
+		if len(headers.TraceContext) == 0 {
+			headers.TraceContext = tracing.Inject(ctx)
+		}
+
+		outboxMessages = append(outboxMessages, &entities.OutboxMessage{
+			Topic:   topic,
+			Headers: headers,
+			Payload: payload,
+		})
+	}
+
+	return p.repository.Add(ctx, outboxMessages...)
+}
+
+func (p *Publisher) encode(ctx context.Context, topic string, message *pubsub.Message[any]) ([]byte, error) {
+	if codec.IsJSON(message.Headers.ContentType) {
+		return json.Marshal(message.Payload.Data)
+	}
+
+	payloadCodec, err := p.codecs.Get(message.Headers.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return payloadCodec.Encode(ctx, topic, message.Payload.Data)
+}
+
+func (p *Publisher) Close(ctx context.Context) error {
+	return nil
+}
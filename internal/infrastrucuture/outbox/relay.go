@@ -0,0 +1,260 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/retry"
+	"github.com/google/uuid"
+)
+
+var relayRetryPolicy = retry.Policy{
+	InitialInterval: time.Second,
+	MaxInterval:     time.Minute,
+	Multiplier:      2,
+}
+
+const (
+	relayLeaseName      = "outbox_relay"
+	leaseReleaseTimeout = 5 * time.Second
+)
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts parks a message after that many failed publishes; zero retries forever.
+	MaxAttempts int
+	// LeaseDuration bounds how long a replica relays without renewing its lease.
+	LeaseDuration time.Duration
+}
+
+// Relay publishes pending outbox messages in sequence order. Only the replica holding the relay lease
+// publishes, so replicas do not race each other. A message that fails to publish holds back the later
+// messages of its key until its backoff elapses, so per-key ordering is kept while other keys go on, and is
+// parked once it reaches MaxAttempts. A key with a parked message stays held until the parked message is
+// dealt with. Messages without a key are not ordered and hold nothing back. Messages keep their ID across
+// attempts, which lets consumers drop the duplicates produced when a publish succeeds but MarkSent fails.
+type Relay struct {
+	repository interfaces.OutboxRepositoryPort
+	leases     interfaces.LeaseRepositoryPort
+	publisher  pubsub.MessagePublisher[any]
+	logger     logger.Logger
+	config     RelayConfig
+	owner      string
+	started    atomic.Bool
+	stopped    chan struct{}
+}
+
+func NewRelay(
+	repository interfaces.OutboxRepositoryPort,
+	leases interfaces.LeaseRepositoryPort,
+	publisher pubsub.MessagePublisher[any],
+	logger logger.Logger,
+	config RelayConfig,
+) *Relay {
+	return &Relay{
+		repository: repository,
+		leases:     leases,
+		publisher:  publisher,
+		logger:     logger,
+		config:     config,
+		owner:      leaseOwner(),
+		stopped:    make(chan struct{}),
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	r.started.Store(true)
+	defer close(r.stopped)
+
+	r.logger.Info("Outbox relay started",
+		"poll_interval", r.config.PollInterval.String(),
+		"batch_size", r.config.BatchSize,
+		"lease_owner", r.owner,
+	)
+	defer r.releaseLease()
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			if err := r.relay(ctx); err != nil {
+				r.logger.Error("Outbox relay iteration failed", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Shutdown waits for Run to return and flushes what is left, so messages written by the last handlers are
+// published before the publisher is closed. Messages that could not be published stay in the outbox and are
+// relayed on the next start.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	if r.started.Load() {
+		select {
+		case <-r.stopped:
+		case <-ctx.Done():
+			return fmt.Errorf("wait for outbox relay to stop: %w", ctx.Err())
+		}
+	}
+
+	flushErr := r.relay(ctx)
+	r.releaseLease()
+
+	pending, err := r.repository.CountPending(ctx)
+	if err != nil {
+		return errors.Join(flushErr, fmt.Errorf("count pending outbox messages: %w", err))
+	}
+	if pending > 0 {
+		r.logger.Warn("Outbox messages left pending at shutdown", "pending", pending)
+	}
+	return flushErr
+}
+
+// relay flushes the outbox while holding the lease. The flush is cut short when the lease would expire, so
+// another replica never takes over while this one is still publishing.
+func (r *Relay) relay(ctx context.Context) error {
+	acquiredAt := time.Now()
+	acquired, err := r.leases.Acquire(ctx, relayLeaseName, r.owner, r.config.LeaseDuration)
+	if err != nil {
+		return fmt.Errorf("acquire outbox relay lease: %w", err)
+	}
+	if !acquired {
+		r.logger.Debug("Outbox relay lease held by another replica")
+		return nil
+	}
+
+	leaseCtx, cancel := context.WithDeadline(ctx, acquiredAt.Add(r.config.LeaseDuration))
+	defer cancel()
+	return r.Flush(leaseCtx)
+}
+
+func (r *Relay) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), leaseReleaseTimeout)
+	defer cancel()
+
+	if err := r.leases.Release(ctx, relayLeaseName, r.owner); err != nil {
+		r.logger.Warn("Failed to release outbox relay lease", "error", err.Error())
+	}
+}
+
+// Flush publishes pending messages until the outbox is exhausted, skipping the keys that are held back.
+// Callers must hold the relay lease.
+func (r *Relay) Flush(ctx context.Context) error {
+	parkedKeys, err := r.repository.ParkedKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch parked outbox keys: %w", err)
+	}
+	held := make(map[string]bool, len(parkedKeys))
+	for _, key := range parkedKeys {
+		holdKey(held, key)
+	}
+
+	var afterSequence int64
+	for {
+		messages, err := r.repository.FetchPending(ctx, afterSequence, r.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("fetch pending outbox messages: %w", err)
+		}
+
+		for _, message := range messages {
+			afterSequence = message.Sequence
+			if held[message.Headers.Key] {
+				continue
+			}
+			if time.Now().Before(message.NextAttemptAt) {
+				holdKey(held, message.Headers.Key)
+				continue
+			}
+
+			if err := r.publish(ctx, message); err != nil {
+				if markErr := r.markFailed(ctx, message, err); markErr != nil {
+					return markErr
+				}
+				holdKey(held, message.Headers.Key)
+				continue
+			}
+
+			if err := r.repository.MarkSent(ctx, message.ID); err != nil {
+				return fmt.Errorf("mark outbox message %s as sent: %w", message.ID, err)
+			}
+		}
+
+		if len(messages) < r.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// markFailed schedules another attempt for a message that failed to publish, or parks it once it reaches
+// MaxAttempts.
+func (r *Relay) markFailed(ctx context.Context, message *entities.OutboxMessage, cause error) error {
+	attempts := message.Attempts + 1
+	if r.config.MaxAttempts > 0 && attempts >= r.config.MaxAttempts {
+		r.logger.Error("Parking outbox message after too many failed publishes",
+			"error", cause.Error(),
+			"outbox_id", message.ID,
+			"topic", message.Topic,
+			"key", message.Headers.Key,
+			"attempts", attempts,
+		)
+		if err := r.repository.MarkParked(ctx, message.ID, cause); err != nil {
+			return fmt.Errorf("mark outbox message %s as parked: %w", message.ID, err)
+		}
+		return nil
+	}
+
+	nextAttemptAt := time.Now().Add(relayRetryPolicy.Backoff(attempts))
+	r.logger.Error("Failed to publish outbox message",
+		"error", cause.Error(),
+		"outbox_id", message.ID,
+		"topic", message.Topic,
+		"key", message.Headers.Key,
+		"attempts", attempts,
+		"next_attempt_at", nextAttemptAt,
+	)
+	if err := r.repository.MarkFailed(ctx, message.ID, cause, nextAttemptAt); err != nil {
+		return fmt.Errorf("mark outbox message %s as failed: %w", message.ID, err)
+	}
+	return nil
+}
+
+// holdKey keeps the later messages of key from being published. Messages without a key are not ordered.
+func holdKey(held map[string]bool, key string) {
+	if key != "" {
+		held[key] = true
+	}
+}
+
+func leaseOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return hostname + "-" + uuid.New().String()
+}
+
+func (r *Relay) publish(ctx context.Context, message *entities.OutboxMessage) error {
+	var data any = message.Payload
+	if codec.IsJSON(message.Headers.ContentType) {
+		if err := json.Unmarshal(message.Payload, &data); err != nil {
+			return fmt.Errorf("unmarshal outbox payload: %w", err)
+		}
+	}
+
+	return r.publisher.Publish(ctx, message.Topic, pubsub.NewMessage(ctx, message.Headers, data))
+}
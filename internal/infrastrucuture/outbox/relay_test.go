@@ -0,0 +1,268 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+)
+
+type fakeOutboxRepository struct {
+	messages []*entities.OutboxMessage
+}
+
+func (r *fakeOutboxRepository) Add(_ context.Context, messages ...*entities.OutboxMessage) error {
+	r.messages = append(r.messages, messages...)
+	return nil
+}
+
+func (r *fakeOutboxRepository) FetchPending(_ context.Context, afterSequence int64, limit int) ([]*entities.OutboxMessage, error) {
+	var pending []*entities.OutboxMessage
+	for _, message := range r.messages {
+		if message.Status == entities.OutboxStatusPending && message.Sequence > afterSequence && len(pending) < limit {
+			copied := *message
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeOutboxRepository) ParkedKeys(context.Context) ([]string, error) {
+	var keys []string
+	for _, message := range r.messages {
+		if message.Status == entities.OutboxStatusParked {
+			keys = append(keys, message.Headers.Key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeOutboxRepository) CountPending(ctx context.Context) (int64, error) {
+	pending, _ := r.FetchPending(ctx, 0, len(r.messages))
+	return int64(len(pending)), nil
+}
+
+func (r *fakeOutboxRepository) MarkSent(_ context.Context, id string) error {
+	r.find(id).Status = entities.OutboxStatusSent
+	return nil
+}
+
+func (r *fakeOutboxRepository) MarkFailed(_ context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	message := r.find(id)
+	message.Attempts++
+	message.LastError = cause.Error()
+	message.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (r *fakeOutboxRepository) MarkParked(_ context.Context, id string, cause error) error {
+	message := r.find(id)
+	message.Attempts++
+	message.LastError = cause.Error()
+	message.Status = entities.OutboxStatusParked
+	return nil
+}
+
+func (r *fakeOutboxRepository) find(id string) *entities.OutboxMessage {
+	for _, message := range r.messages {
+		if message.ID == id {
+			return message
+		}
+	}
+	panic("outbox message not found: " + id)
+}
+
+type fakeLeaseRepository struct {
+	mu     sync.Mutex
+	owner  string
+	expiry time.Time
+}
+
+func (r *fakeLeaseRepository) Acquire(_ context.Context, _, owner string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.owner != "" && r.owner != owner && time.Now().Before(r.expiry) {
+		return false, nil
+	}
+	r.owner, r.expiry = owner, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (r *fakeLeaseRepository) Release(_ context.Context, _, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.owner == owner {
+		r.owner = ""
+	}
+	return nil
+}
+
+type recordingPublisher struct {
+	failTopics map[string]bool
+	published  []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, topic string, messages ...*pubsub.Message[any]) error {
+	if p.failTopics[topic] {
+		return errors.New("message too large")
+	}
+	for _, message := range messages {
+		p.published = append(p.published, message.Headers.MessageID)
+	}
+	return nil
+}
+
+func (p *recordingPublisher) Close(context.Context) error { return nil }
+
+func newPendingMessage(sequence int64, topic, key string) *entities.OutboxMessage {
+	id := strconv.FormatInt(sequence, 10)
+	return &entities.OutboxMessage{
+		ID:       id,
+		Sequence: sequence,
+		Topic:    topic,
+		Headers:  pubsub.Headers{MessageID: id, Key: key},
+		Payload:  []byte(`{}`),
+		Status:   entities.OutboxStatusPending,
+	}
+}
+
+func newTestRelay(repository *fakeOutboxRepository, leases *fakeLeaseRepository, publisher *recordingPublisher, maxAttempts int) *Relay {
+	return NewRelay(repository, leases, publisher, logger.NewNoopLogger(), RelayConfig{
+		PollInterval:  time.Second,
+		BatchSize:     2,
+		MaxAttempts:   maxAttempts,
+		LeaseDuration: time.Minute,
+	})
+}
+
+func TestRelayHoldsBackOnlyTheFailingKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxAttempts   int
+		attempts      int
+		key           string
+		waiting       bool
+		wantPublished []string
+		wantStatus    string
+		wantAttempts  int
+	}{
+		{
+			name:          "failure holds back later messages of its key",
+			maxAttempts:   3,
+			key:           "a",
+			wantPublished: []string{"1", "4", "5"},
+			wantStatus:    entities.OutboxStatusPending,
+			wantAttempts:  1,
+		},
+		{
+			name:          "message waiting on backoff holds back later messages of its key",
+			maxAttempts:   3,
+			attempts:      1,
+			key:           "a",
+			waiting:       true,
+			wantPublished: []string{"1", "4", "5"},
+			wantStatus:    entities.OutboxStatusPending,
+			wantAttempts:  1,
+		},
+		{
+			name:          "parked message keeps holding back its key",
+			maxAttempts:   3,
+			attempts:      2,
+			key:           "a",
+			wantPublished: []string{"1", "4", "5"},
+			wantStatus:    entities.OutboxStatusParked,
+			wantAttempts:  3,
+		},
+		{
+			name:          "no limit retries forever",
+			maxAttempts:   0,
+			attempts:      100,
+			key:           "a",
+			wantPublished: []string{"1", "4", "5"},
+			wantStatus:    entities.OutboxStatusPending,
+			wantAttempts:  101,
+		},
+		{
+			name:          "message without a key holds back nothing",
+			maxAttempts:   3,
+			wantPublished: []string{"1", "3", "4", "5"},
+			wantStatus:    entities.OutboxStatusPending,
+			wantAttempts:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poison := newPendingMessage(2, "oversized", tt.key)
+			poison.Attempts = tt.attempts
+			if tt.waiting {
+				poison.NextAttemptAt = time.Now().Add(time.Hour)
+			}
+			repository := &fakeOutboxRepository{messages: []*entities.OutboxMessage{
+				newPendingMessage(1, "commands", "a"),
+				poison,
+				newPendingMessage(3, "commands", "a"),
+				newPendingMessage(4, "commands", "b"),
+				newPendingMessage(5, "commands", "b"),
+			}}
+			publisher := &recordingPublisher{failTopics: map[string]bool{"oversized": true}}
+			relay := newTestRelay(repository, &fakeLeaseRepository{}, publisher, tt.maxAttempts)
+
+			// The second flush checks that the key stays held once its message is marked.
+			for range 2 {
+				if err := relay.relay(context.Background()); err != nil {
+					t.Fatalf("relay: %v", err)
+				}
+			}
+
+			if !reflect.DeepEqual(publisher.published, tt.wantPublished) {
+				t.Errorf("published = %v, want %v", publisher.published, tt.wantPublished)
+			}
+			if poison.Status != tt.wantStatus {
+				t.Errorf("poison status = %s, want %s", poison.Status, tt.wantStatus)
+			}
+			if poison.Attempts != tt.wantAttempts {
+				t.Errorf("poison attempts = %d, want %d", poison.Attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRelayOnlyPublishesWhileHoldingTheLease(t *testing.T) {
+	repository := &fakeOutboxRepository{messages: []*entities.OutboxMessage{newPendingMessage(1, "commands", "a")}}
+	leases := &fakeLeaseRepository{}
+	leaderPublisher := &recordingPublisher{}
+	followerPublisher := &recordingPublisher{}
+
+	leader := newTestRelay(repository, leases, leaderPublisher, 0)
+	follower := newTestRelay(repository, leases, followerPublisher, 0)
+
+	if ok, _ := leases.Acquire(context.Background(), relayLeaseName, leader.owner, time.Minute); !ok {
+		t.Fatal("leader could not acquire the lease")
+	}
+
+	if err := follower.relay(context.Background()); err != nil {
+		t.Fatalf("follower relay: %v", err)
+	}
+	if len(followerPublisher.published) != 0 {
+		t.Fatalf("follower published %v without the lease", followerPublisher.published)
+	}
+
+	leader.releaseLease()
+
+	if err := follower.relay(context.Background()); err != nil {
+		t.Fatalf("follower relay: %v", err)
+	}
+	if len(followerPublisher.published) != 1 {
+		t.Fatalf("follower published %v after the lease was released", followerPublisher.published)
+	}
+}
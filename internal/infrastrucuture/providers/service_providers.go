@@ -1,35 +1,337 @@
 package providers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/Moreira-Henrique-Pedro/entregador/config"
 	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	repositoryInterfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	repositoryClientInterfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/outbox"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories"
+	mongodb "github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories/memory"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/circuitbreaker"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/health"
 	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
 	"github.com/Moreira-Henrique-Pedro/entregador/pkg/watermill"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	processedMessagesCollectionName = "processed_messages"
+	outboxCollectionName            = "outbox"
+	outboxSequencesCollectionName   = "outbox_sequences"
+	leasesCollectionName            = "leases"
+	residentsCollectionName         = "residents"
 )
 
 type ServiceProviders struct {
 	Logger           logger.Logger
 	MessagePublisher pubsub.MessagePublisher[any]
+	// CommandPublisher is used by handlers to publish follow-up messages. It writes to the outbox
+	// when OUTBOX_ENABLED is set and publishes directly otherwise.
+	CommandPublisher pubsub.MessagePublisher[any]
+	// MongoDatabase is nil when STORAGE_DRIVER is memory; the stores below are then kept in process.
+	MongoDatabase         *mongo.Database
+	TransactionManager    repositoryInterfaces.TransactionManagerPort
+	ProcessedMessageStore pkgEvents.ProcessedMessageStore
+	ResidentRepository    repositoryInterfaces.ResidentRepositoryPort
+	OutboxRelay           *outbox.Relay
+	// Codecs encodes and decodes payloads by content type. SchemaRegistry is nil when SCHEMA_REGISTRY_URL is
+	// empty, in which case Avro is not available.
+	Codecs         *codec.Registry
+	SchemaRegistry schemaregistry.Client
+	// MemoryPubSub backs both publishing and subscribing when PUBSUB_DRIVER is memory; nil otherwise.
+	MemoryPubSub *gochannel.GoChannel
+	// PublisherBreaker and MongoBreaker are nil when CIRCUIT_BREAKER_ENABLED is false.
+	PublisherBreaker *circuitbreaker.Breaker
+	MongoBreaker     *circuitbreaker.Breaker
+	mongoClient      *mongo.Client
 }
 
 func NewServiceProviders(envs *config.Environment, logger logger.Logger) (*ServiceProviders, error) {
 
-	messagePublisher, err := createMessagePublisher(envs, logger)
+	var memoryPubSub *gochannel.GoChannel
+	if envs.Pubsub.Driver == config.PubsubDriverMemory {
+		memoryPubSub = watermill.NewMemoryPubSub(logger)
+	}
+
+	schemaRegistry, err := createSchemaRegistry(envs)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ServiceProviders{
+	codecs, err := createCodecs(schemaRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	messagePublisher, err := createMessagePublisher(envs, memoryPubSub, codecs, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var publisherBreaker *circuitbreaker.Breaker
+	if envs.CircuitBreaker.Enabled {
+		publisherBreaker = createCircuitBreaker(envs, "publisher", nil, pingPublisherBrokers(envs), logger)
+		messagePublisher = circuitbreaker.NewPublisher(messagePublisher, publisherBreaker)
+	}
+
+	serviceProviders := &ServiceProviders{
 		Logger:           logger,
 		MessagePublisher: messagePublisher,
-	}, nil
+		CommandPublisher: messagePublisher,
+		Codecs:           codecs,
+		SchemaRegistry:   schemaRegistry,
+		MemoryPubSub:     memoryPubSub,
+		PublisherBreaker: publisherBreaker,
+	}
+
+	var stores outboxStores
+	if envs.Storage.Driver == config.StorageDriverMemory {
+		stores = serviceProviders.useMemoryStorage()
+	} else {
+		stores, err = serviceProviders.useMongoStorage(envs, logger)
+		if err != nil {
+			logger.Error("Failed to connect to MongoDB", "error", err.Error())
+			_ = messagePublisher.Close(context.Background())
+			return nil, err
+		}
+	}
+
+	if envs.Outbox.Enabled {
+		serviceProviders.CommandPublisher = outbox.NewPublisher(stores.outbox, codecs)
+		serviceProviders.OutboxRelay = outbox.NewRelay(
+			stores.outbox,
+			stores.leases,
+			messagePublisher,
+			logger.With("component", "outbox_relay"),
+			outbox.RelayConfig{
+				PollInterval:  envs.Outbox.PollInterval,
+				BatchSize:     envs.Outbox.BatchSize,
+				MaxAttempts:   envs.Outbox.MaxAttempts,
+				LeaseDuration: envs.Outbox.LeaseDuration,
+			},
+		)
+	}
+
+	return serviceProviders, nil
 }
 
-func createMessagePublisher(cfg *config.Environment, logger logger.Logger) (pubsub.MessagePublisher[any], error) {
-	publisher, err := watermill.NewWatermillPublisher[any](cfg.Pubsub.DeliveryBrokersHosts, logger)
+// outboxStores are created with the other stores and only used when the outbox is enabled.
+type outboxStores struct {
+	outbox repositoryInterfaces.OutboxRepositoryPort
+	leases repositoryInterfaces.LeaseRepositoryPort
+}
+
+func (s *ServiceProviders) useMongoStorage(envs *config.Environment, logger logger.Logger) (outboxStores, error) {
+	mongoClient, err := createMongoClient(envs)
+	if err != nil {
+		return outboxStores{}, err
+	}
+
+	if envs.CircuitBreaker.Enabled {
+		s.MongoBreaker = createCircuitBreaker(envs, "mongodb", mongodb.IsMongoFailure, s.PingMongo, logger)
+	}
+
+	s.mongoClient = mongoClient
+	s.MongoDatabase = mongoClient.Database(envs.MongoDB.Database)
+	s.TransactionManager = mongodb.NewMongoTransactionManager(mongoClient)
+	s.ProcessedMessageStore = repositories.NewMongoDBProcessedMessageRepository(
+		s.CollectionClient(processedMessagesCollectionName),
+		envs.MongoDB.ProcessedMessagesTTL,
+	)
+	s.ResidentRepository = repositories.NewMongoDBResidentRepository(s.CollectionClient(residentsCollectionName))
+
+	stores := outboxStores{leases: repositories.NewMongoDBLeaseRepository(s.CollectionClient(leasesCollectionName))}
+	if envs.Outbox.Enabled {
+		stores.outbox = repositories.NewMongoDBOutboxRepository(
+			s.CollectionClient(outboxCollectionName),
+			s.CollectionClient(outboxSequencesCollectionName),
+			envs.Outbox.Retention,
+		)
+	}
+	return stores, nil
+}
+
+func (s *ServiceProviders) useMemoryStorage() outboxStores {
+	s.TransactionManager = memory.NewTransactionManager()
+	s.ProcessedMessageStore = memory.NewProcessedMessageRepository()
+	s.ResidentRepository = memory.NewResidentRepository()
+
+	return outboxStores{
+		outbox: memory.NewOutboxRepository(),
+		leases: memory.NewLeaseRepository(),
+	}
+}
+
+// CollectionClient returns a client for the named collection, guarded by MongoBreaker when enabled.
+func (s *ServiceProviders) CollectionClient(name string) repositoryClientInterfaces.MongoClientCollectionPort {
+	client := mongodb.NewMongoCollectionClient(s.MongoDatabase.Collection(name))
+	if s.MongoBreaker == nil {
+		return client
+	}
+	return mongodb.NewCircuitBreakerCollectionClient(client, s.MongoBreaker)
+}
+
+// CircuitBreakers returns the enabled circuit breakers.
+func (s *ServiceProviders) CircuitBreakers() []*circuitbreaker.Breaker {
+	var breakers []*circuitbreaker.Breaker
+	for _, breaker := range []*circuitbreaker.Breaker{s.PublisherBreaker, s.MongoBreaker} {
+		if breaker != nil {
+			breakers = append(breakers, breaker)
+		}
+	}
+	return breakers
+}
+
+// PingMongo checks that the primary MongoDB node answers. It passes when MongoDB is not used.
+func (s *ServiceProviders) PingMongo(ctx context.Context) error {
+	if s.mongoClient == nil {
+		return nil
+	}
+	return s.mongoClient.Ping(ctx, readpref.Primary())
+}
+
+// CheckPublisher reports the health of MessagePublisher when it supports health checks.
+func (s *ServiceProviders) CheckPublisher(ctx context.Context) error {
+	checker, ok := s.MessagePublisher.(health.Reporter)
+	if !ok {
+		return nil
+	}
+	return checker.HealthCheck(ctx)
+}
+
+// Close flushes the outbox, closes the publisher and then disconnects MongoDB, which the outbox needs.
+func (s *ServiceProviders) Close(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if s.OutboxRelay != nil {
+		if err := s.OutboxRelay.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush outbox: %w", err))
+		}
+	}
+
+	if s.MessagePublisher != nil {
+		if err := s.MessagePublisher.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close message publisher: %w", err))
+		}
+	}
+
+	if s.mongoClient != nil {
+		if err := s.mongoClient.Disconnect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("disconnect mongodb: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// createCircuitBreaker probes the dependency with probe once the breaker is half-open, so consumers paused
+// while it was open only resume after the dependency answered.
+func createCircuitBreaker(envs *config.Environment, name string, isFailure func(error) bool, probe func(ctx context.Context) error, logger logger.Logger) *circuitbreaker.Breaker {
+	return circuitbreaker.New(circuitbreaker.Config{
+		Name:                name,
+		FailureThreshold:    envs.CircuitBreaker.FailureThreshold,
+		OpenTimeout:         envs.CircuitBreaker.OpenTimeout,
+		HalfOpenMaxRequests: envs.CircuitBreaker.HalfOpenMaxRequests,
+		IsFailure:           isFailure,
+		Probe:               probe,
+		ProbeTimeout:        envs.Health.CheckTimeout,
+	}, logger)
+}
+
+// pingPublisherBrokers probes the publisher breaker. The memory pub/sub has no broker to reach.
+func pingPublisherBrokers(envs *config.Environment) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if envs.Pubsub.Driver == config.PubsubDriverMemory {
+			return nil
+		}
+		return watermill.PingBrokers(ctx, envs.Pubsub.DeliveryBrokersHosts)
+	}
+}
+
+// createSchemaRegistry returns the registry client and registers the schemas of SCHEMA_REGISTRY_SCHEMAS_DIR,
+// which is how the memory registry gets the schemas the codecs encode with.
+func createSchemaRegistry(cfg *config.Environment) (schemaregistry.Client, error) {
+	var client schemaregistry.Client
+	switch cfg.SchemaRegistry.URL {
+	case "":
+		if cfg.SchemaRegistry.SchemasDir != "" {
+			return nil, errors.New("SCHEMA_REGISTRY_SCHEMAS_DIR requires SCHEMA_REGISTRY_URL")
+		}
+		return nil, nil
+	case config.SchemaRegistryMemory:
+		client = schemaregistry.NewMemoryRegistry()
+	default:
+		client = schemaregistry.NewHTTPClient(schemaregistry.Config{
+			URL:      cfg.SchemaRegistry.URL,
+			Username: cfg.SchemaRegistry.Username,
+			Password: cfg.SchemaRegistry.Password,
+			Timeout:  cfg.SchemaRegistry.Timeout,
+		})
+	}
+
+	if cfg.SchemaRegistry.SchemasDir == "" {
+		return client, nil
+	}
+
+	schemas, err := schemaregistry.LoadDir(cfg.SchemaRegistry.SchemasDir)
+	if err != nil {
+		return nil, fmt.Errorf("load schemas: %w", err)
+	}
+	if err := schemaregistry.RegisterAll(context.Background(), client, schemas); err != nil {
+		return nil, fmt.Errorf("register schemas: %w", err)
+	}
+	return client, nil
+}
+
+func createCodecs(schemaRegistry schemaregistry.Client) (*codec.Registry, error) {
+	codecs := []codec.Codec{codec.NewProtobufCodec(schemaRegistry)}
+	if schemaRegistry != nil {
+		avroCodec, err := codec.NewAvroCodec(schemaRegistry)
+		if err != nil {
+			return nil, fmt.Errorf("create avro codec: %w", err)
+		}
+		codecs = append(codecs, avroCodec)
+	}
+	return codec.NewRegistry(codecs...), nil
+}
+
+func createMessagePublisher(cfg *config.Environment, memoryPubSub *gochannel.GoChannel, codecs *codec.Registry, logger logger.Logger) (pubsub.MessagePublisher[any], error) {
+	if memoryPubSub != nil {
+		return watermill.WrapWatermillPublisher[any](memoryPubSub, codecs), nil
+	}
+
+	publisher, err := watermill.NewWatermillPublisher[any](cfg.Pubsub.DeliveryBrokersHosts, codecs, logger)
 	if err != nil {
 		logger.Error("Failed to create Watermill publisher", "error", err.Error())
 		return nil, err
 	}
 	return publisher, nil
 }
+
+func createMongoClient(cfg *config.Environment) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDB.URI))
+	if err != nil {
+		return nil, fmt.Errorf("connect mongodb: %w", err)
+	}
+	return client, nil
+}
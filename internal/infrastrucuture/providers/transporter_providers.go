@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/config"
@@ -24,7 +25,7 @@ func NewTransporterProviders(
 	subscriberCfg *subscriberConfig.SubscriberConfig,
 	serviceProviders *ServiceProviders,
 ) (*TransporterProviders, error) {
-	publisher := serviceProviders.MessagePublisher
+	publisher := serviceProviders.CommandPublisher
 
 	residentTransporter := transporters.NewCreateResidentTransporter(
 		publisher,
@@ -34,7 +35,9 @@ func NewTransporterProviders(
 
 	registry := pkgEvents.NewEventHandlerRegistry()
 
-	register(registry, events.CreateResidentEventType, residentTransporter.Handle)
+	if err := register(registry, events.CreateResidentEventType, residentTransporter.Handle); err != nil {
+		return nil, fmt.Errorf("register %s handler: %w", events.CreateResidentEventType, err)
+	}
 
 	return &TransporterProviders{
 		Registry: registry,
@@ -45,10 +48,10 @@ func register[T any](
 	registry *pkgEvents.EventHandlerRegistry,
 	eventType string,
 	handlerFunc func(context.Context, *T) error,
-) {
+) error {
 	var zero T
 
-	registry.RegisterHandler(
+	return registry.RegisterHandler(
 		eventType,
 		func(ctx context.Context, payload any) error {
 			return handlerFunc(ctx, payload.(*T))
@@ -0,0 +1,103 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/circuitbreaker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CircuitBreakerCollectionClient guards the operations of a collection client with a circuit breaker.
+// Index management runs at startup and is not guarded.
+type CircuitBreakerCollectionClient struct {
+	next    interfaces.MongoClientCollectionPort
+	breaker *circuitbreaker.Breaker
+}
+
+func NewCircuitBreakerCollectionClient(next interfaces.MongoClientCollectionPort, breaker *circuitbreaker.Breaker) interfaces.MongoClientCollectionPort {
+	return &CircuitBreakerCollectionClient{next: next, breaker: breaker}
+}
+
+// IsMongoFailure reports whether err means MongoDB is unhealthy rather than the request being rejected.
+func IsMongoFailure(err error) bool {
+	return !errors.Is(err, mongo.ErrNoDocuments) &&
+		!mongo.IsDuplicateKeyError(err) &&
+		!errors.Is(err, context.Canceled)
+}
+
+func (c *CircuitBreakerCollectionClient) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (result *mongo.InsertOneResult, err error) {
+	err = c.breaker.Execute(func() error {
+		result, err = c.next.InsertOne(ctx, document, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (c *CircuitBreakerCollectionClient) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	var result *mongo.SingleResult
+	err := c.breaker.Execute(func() error {
+		result = c.next.FindOne(ctx, filter, opts...)
+		return result.Err()
+	})
+	if result == nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return result
+}
+
+func (c *CircuitBreakerCollectionClient) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (cursor *mongo.Cursor, err error) {
+	err = c.breaker.Execute(func() error {
+		cursor, err = c.next.Find(ctx, filter, opts...)
+		return err
+	})
+	return cursor, err
+}
+
+func (c *CircuitBreakerCollectionClient) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (result *mongo.UpdateResult, err error) {
+	err = c.breaker.Execute(func() error {
+		result, err = c.next.UpdateOne(ctx, filter, update, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (c *CircuitBreakerCollectionClient) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	var result *mongo.SingleResult
+	err := c.breaker.Execute(func() error {
+		result = c.next.FindOneAndUpdate(ctx, filter, update, opts...)
+		return result.Err()
+	})
+	if result == nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return result
+}
+
+func (c *CircuitBreakerCollectionClient) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (result *mongo.DeleteResult, err error) {
+	err = c.breaker.Execute(func() error {
+		result, err = c.next.DeleteOne(ctx, filter, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (c *CircuitBreakerCollectionClient) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (count int64, err error) {
+	err = c.breaker.Execute(func() error {
+		count, err = c.next.CountDocuments(ctx, filter, opts...)
+		return err
+	})
+	return count, err
+}
+
+func (c *CircuitBreakerCollectionClient) EnsureUniqueIndex(keys interface{}) error {
+	return c.next.EnsureUniqueIndex(keys)
+}
+
+func (c *CircuitBreakerCollectionClient) EnsureTTLIndex(field string, ttl time.Duration) error {
+	return c.next.EnsureTTLIndex(field, ttl)
+}
@@ -2,10 +2,16 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MongoCollectionClient struct {
@@ -17,23 +23,52 @@ func NewMongoCollectionClient(collection *mongo.Collection) interfaces.MongoClie
 }
 
 func (m *MongoCollectionClient) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
-	return m.collection.UpdateOne(ctx, filter, update, opts...)
+	ctx, finish := m.startOperation(ctx, "updateOne")
+	result, err := m.collection.UpdateOne(ctx, filter, update, opts...)
+	finish(err)
+	return result, err
+}
+
+func (m *MongoCollectionClient) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	ctx, finish := m.startOperation(ctx, "findOneAndUpdate")
+	result := m.collection.FindOneAndUpdate(ctx, filter, update, opts...)
+	finish(ignoreNoDocuments(result.Err()))
+	return result
 }
 
 func (m *MongoCollectionClient) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
-	return m.collection.InsertOne(ctx, document, opts...)
+	ctx, finish := m.startOperation(ctx, "insertOne")
+	result, err := m.collection.InsertOne(ctx, document, opts...)
+	finish(err)
+	return result, err
 }
 
 func (m *MongoCollectionClient) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
-	return m.collection.FindOne(ctx, filter, opts...)
+	ctx, finish := m.startOperation(ctx, "findOne")
+	result := m.collection.FindOne(ctx, filter, opts...)
+	finish(ignoreNoDocuments(result.Err()))
+	return result
 }
 
 func (m *MongoCollectionClient) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
-	return m.collection.Find(ctx, filter, opts...)
+	ctx, finish := m.startOperation(ctx, "find")
+	cursor, err := m.collection.Find(ctx, filter, opts...)
+	finish(err)
+	return cursor, err
 }
 
 func (m *MongoCollectionClient) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
-	return m.collection.DeleteOne(ctx, filter, opts...)
+	ctx, finish := m.startOperation(ctx, "deleteOne")
+	result, err := m.collection.DeleteOne(ctx, filter, opts...)
+	finish(err)
+	return result, err
+}
+
+func (m *MongoCollectionClient) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	ctx, finish := m.startOperation(ctx, "countDocuments")
+	count, err := m.collection.CountDocuments(ctx, filter, opts...)
+	finish(err)
+	return count, err
 }
 
 func (m *MongoCollectionClient) EnsureUniqueIndex(keys interface{}) error {
@@ -44,3 +79,40 @@ func (m *MongoCollectionClient) EnsureUniqueIndex(keys interface{}) error {
 	_, err := m.collection.Indexes().CreateOne(context.Background(), indexModel)
 	return err
 }
+
+func (m *MongoCollectionClient) EnsureTTLIndex(field string, ttl time.Duration) error {
+	indexModel := mongo.IndexModel{
+		Keys:    map[string]interface{}{field: 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(ttl.Seconds())),
+	}
+	_, err := m.collection.Indexes().CreateOne(context.Background(), indexModel)
+	return err
+}
+
+// startOperation traces and times a collection operation until the returned function is called with its error.
+func (m *MongoCollectionClient) startOperation(ctx context.Context, operation string) (context.Context, func(error)) {
+	collection := m.collection.Name()
+	ctx, span := tracing.Start(ctx, operation+" "+collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameMongoDB,
+			semconv.DBCollectionName(collection),
+			semconv.DBOperationName(operation),
+		),
+	)
+
+	start := time.Now()
+	return ctx, func(err error) {
+		metrics.MongoOperationDuration.WithLabelValues(collection, operation, metrics.Result(err)).
+			Observe(time.Since(start).Seconds())
+		tracing.End(span, err)
+	}
+}
+
+// ignoreNoDocuments keeps lookups that find nothing from being reported as failed spans.
+func ignoreNoDocuments(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package mongodb
+
+import (
+	"context"
+
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoTransactionManager struct {
+	client *mongo.Client
+}
+
+func NewMongoTransactionManager(client *mongo.Client) interfaces.TransactionManagerPort {
+	return &MongoTransactionManager{client: client}
+}
+
+func (m *MongoTransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
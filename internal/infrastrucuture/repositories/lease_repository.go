@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	client "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoDBLeaseRepository struct {
+	collection client.MongoClientCollectionPort
+}
+
+// NewMongoDBLeaseRepository stores one document per lease. Expiry is compared against the clock of the
+// replica acquiring the lease, so lease durations must be well above the clock skew between replicas.
+func NewMongoDBLeaseRepository(client client.MongoClientCollectionPort) interfaces.LeaseRepositoryPort {
+	return &MongoDBLeaseRepository{
+		collection: client,
+	}
+}
+
+// Acquire upserts the lease when it is free, expired or already ours. When another owner holds it the
+// filter does not match and the upsert collides with the existing document.
+func (r *MongoDBLeaseRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": name,
+			"$or": bson.A{
+				bson.M{"owner": owner},
+				bson.M{"expires_at": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *MongoDBLeaseRepository) Release(ctx context.Context, name, owner string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": name, "owner": owner},
+		bson.M{"$set": bson.M{"expires_at": time.Now().UTC()}},
+	)
+	return err
+}
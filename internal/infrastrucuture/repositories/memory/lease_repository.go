@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type lease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+type LeaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+func NewLeaseRepository() *LeaseRepository {
+	return &LeaseRepository{leases: make(map[string]lease)}
+}
+
+func (r *LeaseRepository) Acquire(_ context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if current, ok := r.leases[name]; ok && current.owner != owner && now.Before(current.expiresAt) {
+		return false, nil
+	}
+	r.leases[name] = lease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (r *LeaseRepository) Release(_ context.Context, name, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.leases[name]; ok && current.owner == owner {
+		delete(r.leases, name)
+	}
+	return nil
+}
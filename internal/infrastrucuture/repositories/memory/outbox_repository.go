@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+)
+
+// OutboxRepository keeps outbox messages in insertion order, which is also their sequence order. Sent
+// messages are kept, so it is only meant for tests and local runs.
+type OutboxRepository struct {
+	mu       sync.Mutex
+	nextID   int
+	messages []*entities.OutboxMessage
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+func (r *OutboxRepository) Add(_ context.Context, messages ...*entities.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, message := range messages {
+		r.nextID++
+		stored := *message
+		stored.ID = strconv.Itoa(r.nextID)
+		stored.Sequence = int64(r.nextID)
+		if stored.CreatedAt.IsZero() {
+			stored.CreatedAt = time.Now().UTC()
+		}
+		if stored.NextAttemptAt.IsZero() {
+			stored.NextAttemptAt = stored.CreatedAt
+		}
+		if stored.Status == "" {
+			stored.Status = entities.OutboxStatusPending
+		}
+		r.messages = append(r.messages, &stored)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) FetchPending(_ context.Context, afterSequence int64, limit int) ([]*entities.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []*entities.OutboxMessage
+	for _, message := range r.messages {
+		if len(pending) == limit {
+			break
+		}
+		if message.Status == entities.OutboxStatusPending && message.Sequence > afterSequence {
+			copied := *message
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+func (r *OutboxRepository) ParkedKeys(_ context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, message := range r.messages {
+		if message.Status == entities.OutboxStatusParked && !seen[message.Headers.Key] {
+			seen[message.Headers.Key] = true
+			keys = append(keys, message.Headers.Key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *OutboxRepository) CountPending(_ context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending int64
+	for _, message := range r.messages {
+		if message.Status == entities.OutboxStatusPending {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (r *OutboxRepository) MarkSent(_ context.Context, id string) error {
+	return r.update(id, func(message *entities.OutboxMessage) {
+		message.Status = entities.OutboxStatusSent
+		message.SentAt = time.Now().UTC()
+	})
+}
+
+func (r *OutboxRepository) MarkFailed(_ context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	return r.update(id, func(message *entities.OutboxMessage) {
+		message.Attempts++
+		message.LastError = cause.Error()
+		message.NextAttemptAt = nextAttemptAt.UTC()
+	})
+}
+
+func (r *OutboxRepository) MarkParked(_ context.Context, id string, cause error) error {
+	return r.update(id, func(message *entities.OutboxMessage) {
+		message.Attempts++
+		message.LastError = cause.Error()
+		message.Status = entities.OutboxStatusParked
+	})
+}
+
+func (r *OutboxRepository) update(id string, apply func(message *entities.OutboxMessage)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, message := range r.messages {
+		if message.ID == id {
+			apply(message)
+			return nil
+		}
+	}
+	return fmt.Errorf("outbox message %s not found", id)
+}
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+type ProcessedMessageRepository struct {
+	mu        sync.RWMutex
+	processed map[string]bool
+}
+
+func NewProcessedMessageRepository() *ProcessedMessageRepository {
+	return &ProcessedMessageRepository{processed: make(map[string]bool)}
+}
+
+func (r *ProcessedMessageRepository) IsProcessed(_ context.Context, scope, messageID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.processed[scope+":"+messageID], nil
+}
+
+func (r *ProcessedMessageRepository) MarkProcessed(_ context.Context, scope, messageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processed[scope+":"+messageID] = true
+	return nil
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+)
+
+// ResidentRepository keeps residents in memory, ignoring duplicate resident IDs like the MongoDB
+// repository does.
+type ResidentRepository struct {
+	mu        sync.RWMutex
+	residents map[string]*entities.Resident
+}
+
+func NewResidentRepository() *ResidentRepository {
+	return &ResidentRepository{residents: make(map[string]*entities.Resident)}
+}
+
+func (r *ResidentRepository) Insert(_ context.Context, resident *entities.Resident) error {
+	if resident == nil {
+		return errors.New("resident is nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.residents[resident.ResidentID]; ok {
+		return nil
+	}
+
+	stored := *resident
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now().UTC()
+	}
+	stored.UpdatedAt = time.Now().UTC()
+	r.residents[resident.ResidentID] = &stored
+	return nil
+}
+
+// List returns copies of the stored residents.
+func (r *ResidentRepository) List() []entities.Resident {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	residents := make([]entities.Resident, 0, len(r.residents))
+	for _, resident := range r.residents {
+		residents = append(residents, *resident)
+	}
+	return residents
+}
@@ -0,0 +1,15 @@
+package memory
+
+import "context"
+
+// TransactionManager runs fn directly: the memory repositories have no transactions, so writes made before
+// a failure are not rolled back.
+type TransactionManager struct{}
+
+func NewTransactionManager() *TransactionManager {
+	return &TransactionManager{}
+}
+
+func (TransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type Lease struct {
+	Name      string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
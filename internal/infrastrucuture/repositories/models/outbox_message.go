@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxMessage struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Sequence      int64              `bson:"sequence"`
+	Topic         string             `bson:"topic"`
+	Headers       pubsub.Headers     `bson:"headers"`
+	Payload       []byte             `bson:"payload"`
+	Status        string             `bson:"status"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"last_error,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	SentAt        time.Time          `bson:"sent_at,omitempty"`
+}
+
+func OutboxMessageFromEntity(message *entities.OutboxMessage) *OutboxMessage {
+	return &OutboxMessage{
+		ID:            primitive.NewObjectID(),
+		Sequence:      message.Sequence,
+		Topic:         message.Topic,
+		Headers:       message.Headers,
+		Payload:       message.Payload,
+		Status:        message.Status,
+		Attempts:      message.Attempts,
+		LastError:     message.LastError,
+		CreatedAt:     message.CreatedAt,
+		NextAttemptAt: message.NextAttemptAt,
+		SentAt:        message.SentAt,
+	}
+}
+
+func (m *OutboxMessage) ToEntity() *entities.OutboxMessage {
+	return &entities.OutboxMessage{
+		ID:            m.ID.Hex(),
+		Sequence:      m.Sequence,
+		Topic:         m.Topic,
+		Headers:       m.Headers,
+		Payload:       m.Payload,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		NextAttemptAt: m.NextAttemptAt,
+		SentAt:        m.SentAt,
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type ProcessedMessage struct {
+	ID          string    `bson:"_id"`
+	Scope       string    `bson:"scope"`
+	MessageID   string    `bson:"message_id"`
+	ProcessedAt time.Time `bson:"processed_at"`
+}
+
+func ProcessedMessageID(scope, messageID string) string {
+	return scope + ":" + messageID
+}
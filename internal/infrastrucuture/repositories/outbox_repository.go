@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/entities"
+	interfaces "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories"
+	client "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	sentAtField        = "sent_at"
+	sequenceField      = "sequence"
+	outboxSequenceName = "outbox"
+)
+
+type MongoDBOutboxRepository struct {
+	collection client.MongoClientCollectionPort
+	sequences  client.MongoClientCollectionPort
+}
+
+// NewMongoDBOutboxRepository stores outgoing messages, removing sent ones after retention. Sequences are
+// taken from a counter document in the sequences collection.
+func NewMongoDBOutboxRepository(
+	client client.MongoClientCollectionPort,
+	sequences client.MongoClientCollectionPort,
+	retention time.Duration,
+) interfaces.OutboxRepositoryPort {
+	_ = client.EnsureTTLIndex(sentAtField, retention)
+	_ = client.EnsureUniqueIndex(bson.D{{Key: sequenceField, Value: 1}})
+	return &MongoDBOutboxRepository{
+		collection: client,
+		sequences:  sequences,
+	}
+}
+
+// Add inserts the messages in order. When ctx belongs to a Mongo session the inserts join its transaction,
+// and so does the sequence increment: concurrent transactions conflict on the counter and are retried, so
+// sequences are committed in the order they are taken.
+func (r *MongoDBOutboxRepository) Add(ctx context.Context, messages ...*entities.OutboxMessage) error {
+	for _, message := range messages {
+		sequence, err := r.nextSequence(ctx)
+		if err != nil {
+			return fmt.Errorf("next outbox sequence: %w", err)
+		}
+
+		model := models.OutboxMessageFromEntity(message)
+		model.Sequence = sequence
+		if model.CreatedAt.IsZero() {
+			model.CreatedAt = time.Now().UTC()
+		}
+		if model.NextAttemptAt.IsZero() {
+			model.NextAttemptAt = model.CreatedAt
+		}
+		if model.Status == "" {
+			model.Status = entities.OutboxStatusPending
+		}
+
+		if _, err := r.collection.InsertOne(ctx, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoDBOutboxRepository) nextSequence(ctx context.Context) (int64, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	err := r.sequences.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": outboxSequenceName},
+		bson.M{"$inc": bson.M{"value": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	return counter.Value, err
+}
+
+func (r *MongoDBOutboxRepository) FetchPending(ctx context.Context, afterSequence int64, limit int) ([]*entities.OutboxMessage, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"status": entities.OutboxStatusPending, sequenceField: bson.M{"$gt": afterSequence}},
+		options.Find().SetSort(bson.D{{Key: sequenceField, Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var outboxModels []*models.OutboxMessage
+	if err := cursor.All(ctx, &outboxModels); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*entities.OutboxMessage, 0, len(outboxModels))
+	for _, model := range outboxModels {
+		messages = append(messages, model.ToEntity())
+	}
+	return messages, nil
+}
+
+func (r *MongoDBOutboxRepository) ParkedKeys(ctx context.Context) ([]string, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"status": entities.OutboxStatusParked},
+		options.Find().SetProjection(bson.M{"headers.key": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var parked []struct {
+		Headers struct {
+			Key string `bson:"key"`
+		} `bson:"headers"`
+	}
+	if err := cursor.All(ctx, &parked); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(parked))
+	keys := make([]string, 0, len(parked))
+	for _, message := range parked {
+		if key := message.Headers.Key; !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *MongoDBOutboxRepository) CountPending(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"status": entities.OutboxStatusPending})
+}
+
+func (r *MongoDBOutboxRepository) MarkSent(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid outbox message id %s: %w", id, err)
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"status":  entities.OutboxStatusSent,
+			"sent_at": time.Now().UTC(),
+		},
+	})
+	return err
+}
+
+func (r *MongoDBOutboxRepository) MarkFailed(ctx context.Context, id string, cause error, nextAttemptAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid outbox message id %s: %w", id, err)
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"last_error":      cause.Error(),
+			"next_attempt_at": nextAttemptAt.UTC(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
+
+func (r *MongoDBOutboxRepository) MarkParked(ctx context.Context, id string, cause error) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid outbox message id %s: %w", id, err)
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"status":     entities.OutboxStatusParked,
+			"last_error": cause.Error(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	client "github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/repositories/client"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/infrastrucuture/repositories/models"
+	pkgEvents "github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const processedAtField = "processed_at"
+
+type MongoDBProcessedMessageRepository struct {
+	collection client.MongoClientCollectionPort
+}
+
+// NewMongoDBProcessedMessageRepository stores processed message IDs, expiring them after ttl.
+func NewMongoDBProcessedMessageRepository(client client.MongoClientCollectionPort, ttl time.Duration) pkgEvents.ProcessedMessageStore {
+	_ = client.EnsureTTLIndex(processedAtField, ttl)
+	return &MongoDBProcessedMessageRepository{
+		collection: client,
+	}
+}
+
+func (r *MongoDBProcessedMessageRepository) IsProcessed(ctx context.Context, scope, messageID string) (bool, error) {
+	err := r.collection.FindOne(ctx, map[string]interface{}{"_id": models.ProcessedMessageID(scope, messageID)}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *MongoDBProcessedMessageRepository) MarkProcessed(ctx context.Context, scope, messageID string) error {
+	model := &models.ProcessedMessage{
+		ID:          models.ProcessedMessageID(scope, messageID),
+		Scope:       scope,
+		MessageID:   messageID,
+		ProcessedAt: time.Now().UTC(),
+	}
+
+	_, err := r.collection.InsertOne(ctx, model)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+const defaultProbeTimeout = 5 * time.Second
+
+// ErrOpen is returned without calling the dependency while the breaker is open, or half-open with every
+// probe slot taken or a Probe running. It is not classified, so handlers treat it as retryable.
+var ErrOpen = errors.New("circuit breaker is open")
+
+type Config struct {
+	Name string
+	// FailureThreshold is the number of consecutive failures that opens the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting probe requests through.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of concurrent probes allowed while half-open. Each probe that
+	// succeeds counts towards closing the breaker; the first one that fails opens it again.
+	HalfOpenMaxRequests int
+	// IsFailure reports whether err means the dependency is unhealthy. When nil every error counts.
+	IsFailure func(err error) bool
+	// Probe, when set, checks the dependency once OpenTimeout elapses, up to HalfOpenMaxRequests times,
+	// instead of letting callers through: calls are rejected until the probes close the breaker. Every
+	// error it returns counts as a failure, and each call is bounded by ProbeTimeout.
+	Probe        func(ctx context.Context) error
+	ProbeTimeout time.Duration
+}
+
+// StateChangeFunc is called, outside of the breaker's lock, after every transition.
+type StateChangeFunc func(name string, from, to State)
+
+// Breaker stops calling a failing dependency until it has had time to recover.
+type Breaker struct {
+	config Config
+	logger logger.Logger
+
+	mu                  sync.Mutex
+	state               State
+	consecutiveFailures int
+	halfOpenInFlight    int
+	halfOpenSuccesses   int
+	openTimer           *time.Timer
+	listeners           []StateChangeFunc
+}
+
+func New(config Config, logger logger.Logger) *Breaker {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	if config.HalfOpenMaxRequests < 1 {
+		config.HalfOpenMaxRequests = 1
+	}
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = defaultProbeTimeout
+	}
+
+	metrics.CircuitBreakerState.WithLabelValues(config.Name).Set(float64(StateClosed))
+	return &Breaker{
+		config: config,
+		logger: logger.With("circuit_breaker", config.Name),
+		state:  StateClosed,
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.config.Name
+}
+
+// Probes reports whether the breaker checks the dependency itself while half-open.
+func (b *Breaker) Probes() bool {
+	return b.config.Probe != nil
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// OnStateChange registers fn to be notified of every transition.
+func (b *Breaker) OnStateChange(fn StateChangeFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// Execute calls fn unless the breaker rejects the call, and records its outcome.
+func (b *Breaker) Execute(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.record(err)
+	return err
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		metrics.CircuitBreakerRejected.WithLabelValues(b.config.Name).Inc()
+		return ErrOpen
+	case StateHalfOpen:
+		if b.config.Probe != nil || b.halfOpenInFlight >= b.config.HalfOpenMaxRequests {
+			metrics.CircuitBreakerRejected.WithLabelValues(b.config.Name).Inc()
+			return ErrOpen
+		}
+		b.halfOpenInFlight++
+	}
+	return nil
+}
+
+func (b *Breaker) record(err error) {
+	b.recordOutcome(err != nil && (b.config.IsFailure == nil || b.config.IsFailure(err)), err)
+}
+
+func (b *Breaker) recordOutcome(failed bool, err error) {
+	b.mu.Lock()
+	from := b.state
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.consecutiveFailures = 0
+			break
+		}
+		b.consecutiveFailures++
+		if b.consecutiveFailures >= b.config.FailureThreshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		if b.halfOpenInFlight > 0 {
+			b.halfOpenInFlight--
+		}
+		if failed {
+			b.open()
+			break
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.config.HalfOpenMaxRequests {
+			b.setState(StateClosed)
+		}
+	case StateOpen:
+		// A call allowed before the breaker opened finished late; its outcome is no longer relevant.
+	}
+
+	to := b.state
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	b.notify(listeners, from, to, err)
+}
+
+// open must be called with mu held. The breaker moves to half-open on its own once OpenTimeout elapses, so
+// probes can be sent even when nothing calls Execute while it is open, for instance a paused consumer.
+func (b *Breaker) open() {
+	b.setState(StateOpen)
+	if b.openTimer != nil {
+		b.openTimer.Stop()
+	}
+	b.openTimer = time.AfterFunc(b.config.OpenTimeout, b.halfOpen)
+}
+
+func (b *Breaker) halfOpen() {
+	b.mu.Lock()
+	if b.state != StateOpen {
+		b.mu.Unlock()
+		return
+	}
+	b.setState(StateHalfOpen)
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	b.notify(listeners, StateOpen, StateHalfOpen, nil)
+
+	if b.config.Probe != nil {
+		go b.probe()
+	}
+}
+
+// probe runs Probe until the breaker closes, or opens again on the first failure.
+func (b *Breaker) probe() {
+	for b.State() == StateHalfOpen {
+		ctx, cancel := context.WithTimeout(context.Background(), b.config.ProbeTimeout)
+		err := b.config.Probe(ctx)
+		cancel()
+
+		if err != nil {
+			err = fmt.Errorf("probe failed: %w", err)
+		}
+		b.recordOutcome(err != nil, err)
+	}
+}
+
+// setState must be called with mu held.
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.consecutiveFailures = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+
+	metrics.CircuitBreakerState.WithLabelValues(b.config.Name).Set(float64(state))
+	metrics.CircuitBreakerTransitions.WithLabelValues(b.config.Name, state.String()).Inc()
+}
+
+func (b *Breaker) notify(listeners []StateChangeFunc, from, to State, cause error) {
+	if from == to {
+		return
+	}
+
+	if to == StateOpen {
+		b.logger.Warn("Circuit breaker opened",
+			"from", from.String(),
+			"error", cause.Error(),
+			"open_timeout", b.config.OpenTimeout.String(),
+		)
+	} else {
+		b.logger.Info("Circuit breaker state changed", "from", from.String(), "to", to.String())
+	}
+
+	for _, listener := range listeners {
+		listener(b.config.Name, from, to)
+	}
+}
@@ -0,0 +1,97 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+)
+
+var errDependency = errors.New("dependency unavailable")
+
+// waitForState polls, since the breaker leaves the open state on a timer.
+func waitForState(t *testing.T, breaker *Breaker, want State) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for breaker.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", breaker.State(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		probeErrs []error
+		wantState State
+	}{
+		{name: "every probe succeeds", probeErrs: []error{nil, nil}, wantState: StateClosed},
+		{name: "a probe fails", probeErrs: []error{nil, errDependency}, wantState: StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probes := make(chan error)
+			transitions := make(chan State, 8)
+			breaker := New(Config{
+				Name:                "dependency",
+				FailureThreshold:    1,
+				OpenTimeout:         time.Millisecond,
+				HalfOpenMaxRequests: len(tt.probeErrs),
+				Probe: func(context.Context) error {
+					return <-probes
+				},
+			}, logger.NewNoopLogger())
+			breaker.OnStateChange(func(_ string, _, to State) { transitions <- to })
+
+			if err := breaker.Execute(func() error { return errDependency }); !errors.Is(err, errDependency) {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			waitForState(t, breaker, StateHalfOpen)
+
+			// Callers are not let through while the breaker probes.
+			if err := breaker.Execute(func() error { return nil }); !errors.Is(err, ErrOpen) {
+				t.Fatalf("Execute() while probing error = %v, want ErrOpen", err)
+			}
+
+			for _, probeErr := range tt.probeErrs {
+				probes <- probeErr
+			}
+			// Listeners see open, half-open and then the outcome of the probes.
+			for _, want := range []State{StateOpen, StateHalfOpen, tt.wantState} {
+				select {
+				case got := <-transitions:
+					if got != want {
+						t.Fatalf("transition to %s, want %s", got, want)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("no transition to %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerWithoutProbeLetsCallersThroughWhenHalfOpen(t *testing.T) {
+	breaker := New(Config{
+		Name:                "dependency",
+		FailureThreshold:    1,
+		OpenTimeout:         time.Millisecond,
+		HalfOpenMaxRequests: 1,
+	}, logger.NewNoopLogger())
+
+	_ = breaker.Execute(func() error { return errDependency })
+	waitForState(t, breaker, StateHalfOpen)
+
+	if err := breaker.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if breaker.State() != StateClosed {
+		t.Errorf("state = %s, want closed", breaker.State())
+	}
+}
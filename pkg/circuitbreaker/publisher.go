@@ -0,0 +1,36 @@
+package circuitbreaker
+
+import (
+	"context"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/health"
+)
+
+type publisher[T any] struct {
+	next    pubsub.MessagePublisher[T]
+	breaker *Breaker
+}
+
+// NewPublisher guards every Publish call of next with breaker.
+func NewPublisher[T any](next pubsub.MessagePublisher[T], breaker *Breaker) pubsub.MessagePublisher[T] {
+	return &publisher[T]{next: next, breaker: breaker}
+}
+
+func (p *publisher[T]) Publish(ctx context.Context, topic string, messages ...*pubsub.Message[T]) error {
+	return p.breaker.Execute(func() error {
+		return p.next.Publish(ctx, topic, messages...)
+	})
+}
+
+func (p *publisher[T]) Close(ctx context.Context) error {
+	return p.next.Close(ctx)
+}
+
+func (p *publisher[T]) HealthCheck(ctx context.Context) error {
+	reporter, ok := p.next.(health.Reporter)
+	if !ok {
+		return nil
+	}
+	return reporter.HealthCheck(ctx)
+}
@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+	"github.com/hamba/avro/v2"
+)
+
+type avroCodec struct {
+	client  schemaregistry.Client
+	schemas *subjectSchemas
+
+	mu     sync.RWMutex
+	parsed map[int]avro.Schema
+}
+
+// NewAvroCodec encodes payloads with the latest schema of the topic's subject and decodes them with the
+// schema whose ID they carry. Payload types map to records through `avro` struct tags.
+func NewAvroCodec(client schemaregistry.Client) (Codec, error) {
+	if client == nil {
+		return nil, errors.New("avro codec requires a schema registry client")
+	}
+
+	return &avroCodec{
+		client:  client,
+		schemas: newSubjectSchemas(client),
+		parsed:  make(map[int]avro.Schema),
+	}, nil
+}
+
+func (*avroCodec) ContentType() string {
+	return pubsub.ContentTypeAvro
+}
+
+func (c *avroCodec) Encode(ctx context.Context, topic string, data any) ([]byte, error) {
+	latest, err := c.schemas.latest(ctx, schemaregistry.TopicSubject(topic))
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.parse(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := avro.Marshal(schema, data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal avro payload with schema %d: %w", latest.ID, err)
+	}
+	return frame(latest.ID, nil, encoded), nil
+}
+
+func (c *avroCodec) Decode(ctx context.Context, payload []byte, target any) error {
+	schemaID, data, err := unframe(payload)
+	if err != nil {
+		return err
+	}
+
+	writerSchema, err := c.client.SchemaByID(ctx, schemaID)
+	if err != nil {
+		return &schemaLookupError{err: fmt.Errorf("look up schema %d: %w", schemaID, err)}
+	}
+
+	schema, err := c.parse(writerSchema)
+	if err != nil {
+		return err
+	}
+
+	if err := avro.Unmarshal(schema, data, target); err != nil {
+		return fmt.Errorf("unmarshal avro payload with schema %d: %w", schemaID, err)
+	}
+	return nil
+}
+
+func (c *avroCodec) parse(registered *schemaregistry.Schema) (avro.Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.parsed[registered.ID]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := avro.Parse(registered.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("parse avro schema %d: %w", registered.ID, err)
+	}
+
+	c.mu.Lock()
+	c.parsed[registered.ID] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+)
+
+type avroResident struct {
+	Name  string `avro:"name"`
+	Phone string `avro:"phone"`
+}
+
+const (
+	avroResidentV1 = `{"type":"record","name":"Resident","fields":[{"name":"name","type":"string"}]}`
+	avroResidentV2 = `{"type":"record","name":"Resident","fields":[{"name":"name","type":"string"},{"name":"phone","type":"string","default":""}]}`
+)
+
+func TestAvroCodec(t *testing.T) {
+	tests := []struct {
+		name      string
+		schemas   []string
+		wantErr   bool
+		wantPhone string
+	}{
+		{name: "subject without schemas", wantErr: true},
+		{name: "latest schema", schemas: []string{avroResidentV1, avroResidentV2}, wantPhone: "+5511999999999"},
+		// Fields missing from the writer schema are left empty.
+		{name: "older schema", schemas: []string{avroResidentV1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			registry := schemaregistry.NewMemoryRegistry()
+			for _, schema := range tt.schemas {
+				if _, err := registry.Register(ctx, schemaregistry.TopicSubject("residents"), schemaregistry.SchemaTypeAvro, schema); err != nil {
+					t.Fatalf("register schema: %v", err)
+				}
+			}
+
+			avroCodec, err := NewAvroCodec(registry)
+			if err != nil {
+				t.Fatalf("NewAvroCodec() error = %v", err)
+			}
+
+			encoded, err := avroCodec.Encode(ctx, "residents", &avroResident{Name: "Ana", Phone: "+5511999999999"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				// A missing subject will not appear by retrying.
+				if IsTransient(err) {
+					t.Errorf("Encode() error = %v, want a permanent error", err)
+				}
+				return
+			}
+
+			schemaID, _, err := unframe(encoded)
+			if err != nil || schemaID != len(tt.schemas) {
+				t.Fatalf("unframe() = %d, %v, want schema %d", schemaID, err, len(tt.schemas))
+			}
+
+			var decoded avroResident
+			if err := avroCodec.Decode(ctx, encoded, &decoded); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if decoded.Name != "Ana" || decoded.Phone != tt.wantPhone {
+				t.Errorf("Decode() = %+v, want name Ana and phone %q", decoded, tt.wantPhone)
+			}
+		})
+	}
+}
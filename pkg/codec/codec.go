@@ -0,0 +1,106 @@
+package codec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+)
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Codec serializes message data for one content type.
+type Codec interface {
+	ContentType() string
+	// Encode serializes data for topic; codecs backed by a schema registry use the topic's subject.
+	Encode(ctx context.Context, topic string, data any) ([]byte, error)
+	// Decode fills target, a pointer to the handler's payload type.
+	Decode(ctx context.Context, payload []byte, target any) error
+}
+
+// Registry selects a codec by content type. JSON is always available, including on a nil Registry.
+type Registry struct {
+	codecs map[string]Codec
+}
+
+func NewRegistry(codecs ...Codec) *Registry {
+	registry := &Registry{codecs: map[string]Codec{pubsub.ContentTypeJSON: NewJSONCodec()}}
+	for _, codec := range codecs {
+		registry.codecs[codec.ContentType()] = codec
+	}
+	return registry
+}
+
+var defaultRegistry = NewRegistry()
+
+func (r *Registry) Get(contentType string) (Codec, error) {
+	if r == nil {
+		r = defaultRegistry
+	}
+
+	codec, ok := r.codecs[Normalize(contentType)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return codec, nil
+}
+
+func (r *Registry) ContentTypes() []string {
+	if r == nil {
+		r = defaultRegistry
+	}
+
+	contentTypes := make([]string, 0, len(r.codecs))
+	for contentType := range r.codecs {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	return contentTypes
+}
+
+// Normalize drops media type parameters and maps the empty content type to JSON.
+func Normalize(contentType string) string {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return pubsub.ContentTypeJSON
+	}
+	return contentType
+}
+
+// IsJSON reports whether contentType is handled by the JSON codec.
+func IsJSON(contentType string) bool {
+	return Normalize(contentType) == pubsub.ContentTypeJSON
+}
+
+type strictDecodingKey struct{}
+
+// WithStrictDecoding makes Decode reject payload fields that the target type does not declare. Codecs
+// whose format cannot tell, such as Avro, ignore it.
+func WithStrictDecoding(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictDecodingKey{}, true)
+}
+
+func isStrictDecoding(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictDecodingKey{}).(bool)
+	return strict
+}
+
+// schemaLookupError wraps schema registry failures, which unlike malformed payloads may be transient.
+type schemaLookupError struct {
+	err error
+}
+
+func (e *schemaLookupError) Error() string { return e.err.Error() }
+
+func (e *schemaLookupError) Unwrap() error { return e.err }
+
+// IsTransient reports whether a codec failure may succeed on redelivery, such as a schema registry outage.
+func IsTransient(err error) bool {
+	var lookupErr *schemaLookupError
+	return errors.As(err, &lookupErr) && schemaregistry.IsTemporary(lookupErr.err)
+}
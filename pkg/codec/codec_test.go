@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+func TestRegistryGet(t *testing.T) {
+	tests := []struct {
+		name        string
+		registry    *Registry
+		contentType string
+		want        string
+		wantErr     bool
+	}{
+		{name: "empty content type is json", registry: NewRegistry(), want: pubsub.ContentTypeJSON},
+		{name: "media type parameters", registry: NewRegistry(), contentType: " Application/JSON; charset=utf-8", want: pubsub.ContentTypeJSON},
+		{name: "nil registry has json", contentType: pubsub.ContentTypeJSON, want: pubsub.ContentTypeJSON},
+		{name: "registered codec", registry: NewRegistry(NewProtobufCodec(nil)), contentType: pubsub.ContentTypeProtobuf, want: pubsub.ContentTypeProtobuf},
+		{name: "unregistered codec", registry: NewRegistry(), contentType: pubsub.ContentTypeProtobuf, wantErr: true},
+		{name: "nil registry has only json", contentType: pubsub.ContentTypeAvro, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := tt.registry.Get(tt.contentType)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedContentType) {
+					t.Errorf("Get(%q) error = %v, want %v", tt.contentType, err, ErrUnsupportedContentType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get(%q) error = %v", tt.contentType, err)
+			}
+			if codec.ContentType() != tt.want {
+				t.Errorf("Get(%q) = %s codec, want %s", tt.contentType, codec.ContentType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONCodecDecode(t *testing.T) {
+	type resident struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		payload string
+		want    resident
+		wantErr bool
+	}{
+		{name: "known fields", payload: `{"name":"Ana"}`, want: resident{Name: "Ana"}},
+		{name: "unknown field", payload: `{"name":"Ana","floor":3}`, want: resident{Name: "Ana"}},
+		{name: "strict with known fields", strict: true, payload: `{"name":"Ana"}`, want: resident{Name: "Ana"}},
+		{name: "strict with unknown field", strict: true, payload: `{"name":"Ana","floor":3}`, wantErr: true},
+		{name: "malformed", payload: `{"name":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.strict {
+				ctx = WithStrictDecoding(ctx)
+			}
+
+			var got resident
+			err := NewJSONCodec().Decode(ctx, []byte(tt.payload), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+type jsonCodec struct{}
+
+func NewJSONCodec() Codec {
+	return jsonCodec{}
+}
+
+func (jsonCodec) ContentType() string {
+	return pubsub.ContentTypeJSON
+}
+
+func (jsonCodec) Encode(_ context.Context, _ string, data any) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec) Decode(ctx context.Context, payload []byte, target any) error {
+	if !isStrictDecoding(ctx) {
+		return json.Unmarshal(payload, target)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
@@ -0,0 +1,101 @@
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+	"google.golang.org/protobuf/proto"
+)
+
+// firstMessageIndex is the message index list [0], which refers to the first message of the schema file.
+var firstMessageIndex = []byte{0}
+
+type protobufCodec struct {
+	schemas *subjectSchemas
+}
+
+// NewProtobufCodec encodes proto.Message payloads. With a registry client, payloads are written in the
+// wire format with the latest schema ID of the topic's subject; without one, they are plain protobuf.
+// Decoding accepts both.
+func NewProtobufCodec(client schemaregistry.Client) Codec {
+	codec := protobufCodec{}
+	if client != nil {
+		codec.schemas = newSubjectSchemas(client)
+	}
+	return codec
+}
+
+func (protobufCodec) ContentType() string {
+	return pubsub.ContentTypeProtobuf
+}
+
+func (c protobufCodec) Encode(ctx context.Context, topic string, data any) ([]byte, error) {
+	message, ok := data.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf payload must be a proto.Message, got %T", data)
+	}
+
+	encoded, err := proto.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("marshal protobuf payload: %w", err)
+	}
+
+	if c.schemas == nil {
+		return encoded, nil
+	}
+
+	schema, err := c.schemas.latest(ctx, schemaregistry.TopicSubject(topic))
+	if err != nil {
+		return nil, err
+	}
+	return frame(schema.ID, firstMessageIndex, encoded), nil
+}
+
+func (protobufCodec) Decode(ctx context.Context, payload []byte, target any) error {
+	message, ok := target.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf payload type must be a proto.Message, got %T", target)
+	}
+
+	// A protobuf message cannot start with a zero byte, so the wire format header is unambiguous.
+	if _, data, err := unframe(payload); err == nil {
+		if payload, err = skipMessageIndexes(data); err != nil {
+			return err
+		}
+	}
+
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return fmt.Errorf("unmarshal protobuf payload: %w", err)
+	}
+
+	// Only the top-level message is checked; unknown fields of nested messages are still accepted.
+	if isStrictDecoding(ctx) {
+		if unknown := message.ProtoReflect().GetUnknown(); len(unknown) > 0 {
+			return fmt.Errorf("protobuf payload has %d bytes of fields unknown to %T", len(unknown), target)
+		}
+	}
+	return nil
+}
+
+// skipMessageIndexes drops the zigzag varint list that locates the message type within its schema file.
+// The target type is already known, so the indexes are not used.
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, errors.New("read protobuf message index count")
+	}
+	data = data[n:]
+
+	// A count of zero is the short form of the list [0].
+	for i := int64(0); i < count; i++ {
+		if _, n = binary.Varint(data); n <= 0 {
+			return nil, errors.New("read protobuf message index")
+		}
+		data = data[n:]
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package codec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const stringValueSchema = `syntax = "proto3"; message StringValue { string value = 1; }`
+
+func TestProtobufCodec(t *testing.T) {
+	tests := []struct {
+		name         string
+		withRegistry bool
+		wantFramed   bool
+	}{
+		{name: "plain protobuf"},
+		{name: "schema registry wire format", withRegistry: true, wantFramed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			var client schemaregistry.Client
+			schemaID := 0
+			if tt.withRegistry {
+				registry := schemaregistry.NewMemoryRegistry()
+				id, err := registry.Register(ctx, schemaregistry.TopicSubject("residents"), schemaregistry.SchemaTypeProtobuf, stringValueSchema)
+				if err != nil {
+					t.Fatalf("register schema: %v", err)
+				}
+				client, schemaID = registry, id
+			}
+
+			protobufCodec := NewProtobufCodec(client)
+			encoded, err := protobufCodec.Encode(ctx, "residents", wrapperspb.String("Ana"))
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			gotID, _, err := unframe(encoded)
+			if (err == nil) != tt.wantFramed {
+				t.Fatalf("unframe() error = %v, want framed %v", err, tt.wantFramed)
+			}
+			if tt.wantFramed && gotID != schemaID {
+				t.Errorf("schema ID = %d, want %d", gotID, schemaID)
+			}
+
+			decoded := &wrapperspb.StringValue{}
+			if err := protobufCodec.Decode(ctx, encoded, decoded); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if decoded.GetValue() != "Ana" {
+				t.Errorf("Decode() = %q, want %q", decoded.GetValue(), "Ana")
+			}
+		})
+	}
+}
+
+func TestProtobufCodecDecode(t *testing.T) {
+	plain, err := proto.Marshal(wrapperspb.Int64(42))
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	// A Timestamp shares field 1 with Int64Value, and its field 2 is unknown to it.
+	withUnknown, err := proto.Marshal(&timestamppb.Timestamp{Seconds: 42, Nanos: 1})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		payload []byte
+		target  any
+		want    int64
+		wantErr bool
+	}{
+		{name: "plain", payload: plain, target: &wrapperspb.Int64Value{}, want: 42},
+		{name: "framed", payload: frame(1, firstMessageIndex, plain), target: &wrapperspb.Int64Value{}, want: 42},
+		{name: "unknown field", payload: withUnknown, target: &wrapperspb.Int64Value{}, want: 42},
+		{name: "strict with unknown field", strict: true, payload: withUnknown, target: &wrapperspb.Int64Value{}, wantErr: true},
+		{name: "framed without message indexes", payload: frame(1, nil, nil), target: &wrapperspb.Int64Value{}, wantErr: true},
+		{name: "target is not a proto.Message", payload: plain, target: &struct{}{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.strict {
+				ctx = WithStrictDecoding(ctx)
+			}
+
+			err := NewProtobufCodec(nil).Decode(ctx, tt.payload, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.target.(*wrapperspb.Int64Value).GetValue(); got != tt.want {
+				t.Errorf("Decode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
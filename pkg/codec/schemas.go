@@ -0,0 +1,43 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/schemaregistry"
+)
+
+// subjectSchemas caches the latest schema of each subject, so a new version is picked up on restart.
+type subjectSchemas struct {
+	client schemaregistry.Client
+
+	mu      sync.RWMutex
+	schemas map[string]*schemaregistry.Schema
+}
+
+func newSubjectSchemas(client schemaregistry.Client) *subjectSchemas {
+	return &subjectSchemas{
+		client:  client,
+		schemas: make(map[string]*schemaregistry.Schema),
+	}
+}
+
+func (s *subjectSchemas) latest(ctx context.Context, subject string) (*schemaregistry.Schema, error) {
+	s.mu.RLock()
+	schema, ok := s.schemas[subject]
+	s.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := s.client.LatestSchema(ctx, subject)
+	if err != nil {
+		return nil, &schemaLookupError{err: fmt.Errorf("look up latest schema of subject %s: %w", subject, err)}
+	}
+
+	s.mu.Lock()
+	s.schemas[subject] = schema
+	s.mu.Unlock()
+	return schema, nil
+}
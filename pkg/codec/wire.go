@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Payloads written with a schema registry use the Confluent wire format: a zero magic byte and the
+// big-endian schema ID, followed by the encoded data.
+const (
+	magicByte  = 0
+	headerSize = 5
+)
+
+var errNotFramed = errors.New("payload is not in schema registry wire format")
+
+func frame(schemaID int, prefix, data []byte) []byte {
+	framed := make([]byte, headerSize, headerSize+len(prefix)+len(data))
+	framed[0] = magicByte
+	binary.BigEndian.PutUint32(framed[1:headerSize], uint32(schemaID))
+	framed = append(framed, prefix...)
+	return append(framed, data...)
+}
+
+func unframe(payload []byte) (int, []byte, error) {
+	if len(payload) < headerSize || payload[0] != magicByte {
+		return 0, nil, errNotFramed
+	}
+	return int(binary.BigEndian.Uint32(payload[1:headerSize])), payload[headerSize:], nil
+}
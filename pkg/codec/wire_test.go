@@ -0,0 +1,86 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		schemaID int
+		prefix   []byte
+		data     []byte
+		want     []byte
+	}{
+		{name: "without prefix", schemaID: 1, data: []byte("data"), want: []byte{0, 0, 0, 0, 1, 'd', 'a', 't', 'a'}},
+		{name: "with prefix", schemaID: 258, prefix: []byte{0}, data: []byte("x"), want: []byte{0, 0, 0, 1, 2, 0, 'x'}},
+		{name: "empty data", schemaID: 7, want: []byte{0, 0, 0, 0, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			framed := frame(tt.schemaID, tt.prefix, tt.data)
+			if !bytes.Equal(framed, tt.want) {
+				t.Fatalf("frame() = %v, want %v", framed, tt.want)
+			}
+
+			schemaID, data, err := unframe(framed)
+			if err != nil {
+				t.Fatalf("unframe() error = %v", err)
+			}
+			if schemaID != tt.schemaID || !bytes.Equal(data, append(append([]byte{}, tt.prefix...), tt.data...)) {
+				t.Errorf("unframe() = %d, %v, want %d and the prefixed data", schemaID, data, tt.schemaID)
+			}
+		})
+	}
+}
+
+func TestUnframeRejectsUnframedPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "empty", payload: nil},
+		{name: "shorter than the header", payload: []byte{0, 0, 0, 1}},
+		{name: "wrong magic byte", payload: []byte{1, 0, 0, 0, 1, 'x'}},
+		{name: "json", payload: []byte(`{"name":"Ana"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := unframe(tt.payload); !errors.Is(err, errNotFramed) {
+				t.Errorf("unframe(%v) error = %v, want %v", tt.payload, err, errNotFramed)
+			}
+		})
+	}
+}
+
+func TestSkipMessageIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "short form of the first message", data: []byte{0, 'x'}, want: []byte{'x'}},
+		// Zigzag varints: a count of 2, then the indexes 1 and 0.
+		{name: "nested message", data: []byte{4, 2, 0, 'x'}, want: []byte{'x'}},
+		{name: "empty", data: nil, wantErr: true},
+		{name: "negative count", data: []byte{1, 'x'}, wantErr: true},
+		{name: "truncated indexes", data: []byte{4, 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := skipMessageIndexes(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("skipMessageIndexes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("skipMessageIndexes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"context"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+type ContextKey string
+
+const (
+	CorrelationIDKey ContextKey = "correlation_id"
+	MessageIDKey     ContextKey = "message_id"
+	HeadersKey       ContextKey = "headers"
+	HandlerNameKey   ContextKey = "handler_name"
+)
+
+// WithMessageID stores the ID of the message being handled so handlers can derive deterministic IDs from it.
+func WithMessageID(ctx context.Context, messageID string) context.Context {
+	return context.WithValue(ctx, MessageIDKey, messageID)
+}
+
+func MessageIDFromContext(ctx context.Context) string {
+	messageID, _ := ctx.Value(MessageIDKey).(string)
+	return messageID
+}
+
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, CorrelationIDKey, correlationID)
+}
+
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(CorrelationIDKey).(string)
+	return correlationID
+}
+
+// WithMessageHeaders stores the headers, message ID and correlation ID of the message being handled. A message
+// without a correlation ID starts a new flow, identified by its own message ID.
+func WithMessageHeaders(ctx context.Context, headers pubsub.Headers) context.Context {
+	correlationID := headers.CorrelationID
+	if correlationID == "" {
+		correlationID = headers.MessageID
+	}
+
+	ctx = context.WithValue(ctx, HeadersKey, headers)
+	ctx = WithMessageID(ctx, headers.MessageID)
+	return WithCorrelationID(ctx, correlationID)
+}
+
+// HeadersFromContext returns the headers of the message being handled, or zero headers outside of one.
+func HeadersFromContext(ctx context.Context) pubsub.Headers {
+	headers, _ := ctx.Value(HeadersKey).(pubsub.Headers)
+	return headers
+}
+
+// PropagateHeaders fills the correlation and causation IDs of an outgoing message from the message being
+// handled in ctx. IDs already set on headers are kept.
+func PropagateHeaders(ctx context.Context, headers *pubsub.Headers) {
+	if headers.CorrelationID == "" {
+		headers.CorrelationID = CorrelationIDFromContext(ctx)
+	}
+	if headers.CausationID == "" {
+		headers.CausationID = MessageIDFromContext(ctx)
+	}
+}
+
+// WithHandlerName stores the name of the handler being called, for middlewares that act per handler.
+func WithHandlerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, HandlerNameKey, name)
+}
+
+func HandlerNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(HandlerNameKey).(string)
+	return name
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+)
+
+// classifiedError is implemented by errors that decide whether a failed message may be retried.
+// The outermost classified error in a chain wins; unclassified errors are treated as retryable.
+type classifiedError interface {
+	error
+	Permanent() bool
+}
+
+// PermanentError marks a failure that will not succeed on redelivery, such as a malformed payload.
+type PermanentError struct {
+	err error
+}
+
+func NewPermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{err: err}
+}
+
+func (e *PermanentError) Error() string { return e.err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.err }
+
+func (e *PermanentError) Permanent() bool { return true }
+
+// RetryableError marks a transient failure, such as an infrastructure timeout.
+type RetryableError struct {
+	err error
+}
+
+func NewRetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{err: err}
+}
+
+func (e *RetryableError) Error() string { return e.err.Error() }
+
+func (e *RetryableError) Unwrap() error { return e.err }
+
+func (e *RetryableError) Permanent() bool { return false }
+
+// PanicError is returned when handling a message panics. It is permanent, since the same message would
+// panic again on redelivery.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic while handling message: %v", e.Value) }
+
+func (e *PanicError) Permanent() bool { return true }
+
+func IsPermanent(err error) bool {
+	var classified classifiedError
+	if errors.As(err, &classified) {
+		return classified.Permanent()
+	}
+	return false
+}
+
+func IsRetryable(err error) bool {
+	return err != nil && !IsPermanent(err)
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	errCause := errors.New("cause")
+
+	tests := []struct {
+		name          string
+		err           error
+		wantPermanent bool
+		wantRetryable bool
+	}{
+		{name: "nil"},
+		{name: "unclassified", err: errCause, wantRetryable: true},
+		{name: "permanent", err: NewPermanentError(errCause), wantPermanent: true},
+		{name: "retryable", err: NewRetryableError(errCause), wantRetryable: true},
+		{name: "wrapped permanent", err: fmt.Errorf("handle: %w", NewPermanentError(errCause)), wantPermanent: true},
+		{name: "outermost classification wins", err: NewRetryableError(NewPermanentError(errCause)), wantRetryable: true},
+		{name: "panic", err: &PanicError{Value: "boom"}, wantPermanent: true},
+		{name: "validation", err: &ValidationError{PayloadType: "payload"}, wantPermanent: true},
+		{
+			name: "handlers with every failure permanent",
+			err: &HandlersError{Results: []HandlerResult{
+				{Name: "first", Err: NewPermanentError(errCause)},
+				{Name: "second"},
+				{Name: "third", Err: &PanicError{Value: "boom"}},
+			}},
+			wantPermanent: true,
+		},
+		{
+			name: "handlers with a retryable failure",
+			err: &HandlersError{Results: []HandlerResult{
+				{Name: "first", Err: NewPermanentError(errCause)},
+				{Name: "second", Err: errCause},
+			}},
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanent(tt.err); got != tt.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.wantPermanent)
+			}
+			if got := IsRetryable(tt.err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestNewClassifiedErrorKeepsCause(t *testing.T) {
+	errCause := errors.New("cause")
+
+	tests := []struct {
+		name string
+		new  func(error) error
+	}{
+		{name: "permanent", new: NewPermanentError},
+		{name: "retryable", new: NewRetryableError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.new(nil); err != nil {
+				t.Errorf("wrapping nil = %v, want nil", err)
+			}
+
+			err := tt.new(errCause)
+			if !errors.Is(err, errCause) || err.Error() != errCause.Error() {
+				t.Errorf("error = %v, want it to wrap %v", err, errCause)
+			}
+		})
+	}
+}
@@ -3,77 +3,296 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
 	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
+	validator "github.com/go-playground/validator/v10"
 )
 
-type ContextKey string
-
-const CorrelationIDKey ContextKey = "correlation_id"
-
 type EventBus struct {
 	eventHandlerRegistry *EventHandlerRegistry
+	processedMessages    ProcessedMessageStore
+	processedScope       string
+	codecs               *codec.Registry
+	topic                string
+	consumerGroup        string
+	middlewares          []Middleware
+	eventTypeMiddlewares map[string][]Middleware
+	validate             *validator.Validate
+	unknownEventTypes    UnknownEventTypePolicy
+	decoding             DecodingMode
 }
 
 type EventBusDependencies struct {
 	EventHandlerRegistry *EventHandlerRegistry
+	// ProcessedMessageStore is optional; when set, handlers that already handled a message within
+	// ProcessedMessageScope are skipped, before the payload is decoded.
+	ProcessedMessageStore ProcessedMessageStore
+	ProcessedMessageScope string
+	// Codecs decodes payloads by their content type; when nil, only JSON payloads are accepted.
+	Codecs *codec.Registry
+	// Topic and ConsumerGroup label the metrics recorded by the bus.
+	Topic         string
+	ConsumerGroup string
+	// Middlewares wrap every handler, the first one being the outermost, and EventTypeMiddlewares then wrap
+	// the handlers of their event type. Both run inside the built-in tracing, timing and logging.
+	Middlewares          []Middleware
+	EventTypeMiddlewares map[string][]Middleware
+	// UnknownEventTypes and Decoding default to UnknownEventTypeIgnore and DecodingLenient.
+	UnknownEventTypes UnknownEventTypePolicy
+	Decoding          DecodingMode
 }
 
 func NewEventBus(props EventBusDependencies) *EventBus {
+	middlewares := []Middleware{Tracing(), Timing(props.Topic, props.ConsumerGroup), Logging()}
+	middlewares = append(middlewares, props.Middlewares...)
+
 	return &EventBus{
 		eventHandlerRegistry: props.EventHandlerRegistry,
+		processedMessages:    props.ProcessedMessageStore,
+		processedScope:       props.ProcessedMessageScope,
+		codecs:               props.Codecs,
+		topic:                props.Topic,
+		consumerGroup:        props.ConsumerGroup,
+		middlewares:          middlewares,
+		eventTypeMiddlewares: props.EventTypeMiddlewares,
+		validate:             newPayloadValidator(),
+		unknownEventTypes:    props.UnknownEventTypes,
+		decoding:             props.Decoding,
 	}
 }
 
-func (e *EventBus) Handle(ctx context.Context, msg *pubsub.Message[any]) error {
+func (e *EventBus) Handle(ctx context.Context, msg *pubsub.Message[any]) (err error) {
+	ctx, span := tracing.Start(ctx, "EventBus.Handle")
+	defer func() { tracing.End(span, err) }()
+
 	logger := logger.GetLoggerFromContext(ctx)
 
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = e.recoverPanic(msg.Headers, recovered, logger)
+		}
+	}()
+
 	if msg == nil || msg.Headers.EventType == "" {
-		logger.Debug("Unrecognized event")
-		return nil
+		return e.handleUnknownEventType("", logger)
+	}
+
+	version := msg.Headers.EventVersion
+	if version == 0 {
+		version = DefaultEventVersion
+	}
+
+	handlers, upcasters, err := e.eventHandlerRegistry.Resolve(msg.Headers.EventType, version)
+	if errors.Is(err, ErrEventTypeNotRegistered) {
+		metrics.UnhandledEventTypes.WithLabelValues(e.topic, e.consumerGroup, msg.Headers.EventType).Inc()
+		return e.handleUnknownEventType(msg.Headers.EventType, logger)
 	}
+	if err != nil {
+		logger.Error("Cannot handle event version", map[string]any{
+			"error":         err.Error(),
+			"event_version": version,
+		})
+		return NewPermanentError(err)
+	}
+
+	ctx = WithMessageHeaders(ctx, msg.Headers)
 
-	handler, err := e.eventHandlerRegistry.GetEventHandlerByEventType(msg.Headers.EventType)
-	if handler == nil || err != nil {
-		logger.Debug("No Handler registered for this event type")
+	// A redelivery is skipped before its payload is decoded, so a message already handled is never
+	// dead-lettered because it no longer decodes, for instance after switching to strict decoding.
+	handlers, err = e.pendingHandlers(ctx, handlers, logger)
+	if err != nil {
+		return err
+	}
+	if len(handlers) == 0 {
+		logger.Info("Message already processed, skipping")
 		return nil
 	}
 
-	payload, err := e.processPayload(msg, handler, logger)
+	handler := &handlers[0]
+	payloadType := handler.PayloadType
+	if len(upcasters) > 0 {
+		payloadType = upcasters[0].PayloadType
+	}
+
+	decode := func() (any, error) {
+		payload, err := e.processPayload(ctx, msg, payloadType, logger)
+		if err == nil {
+			payload, err = e.upcast(ctx, payload, upcasters, logger)
+		}
+		if err == nil {
+			err = e.validatePayloadType(payload, handler, logger)
+		}
+		return payload, err
+	}
+
+	payload, err := decode()
+	if err == nil {
+		err = validatePayload(e.validate, payload)
+	}
 	if err != nil {
 		logger.Error("Failed to process payload", map[string]any{
 			"error":        err.Error(),
-			"payload_type": handler.PayloadType.String(),
+			"payload_type": payloadType.String(),
 		})
 		return err
 	}
 
-	return e.executeHandler(ctx, handler, payload, logger)
+	if len(handlers) == 1 {
+		return e.executeHandler(ctx, msg.Headers.EventType, handler, payload)
+	}
+	return e.executeHandlers(ctx, msg.Headers.EventType, handlers, payload, decode, logger)
+}
+
+// pendingHandlers returns the handlers that have not handled the message yet, so a retry after a partial
+// fan-out only runs the handlers that failed. Each handler is recorded once it succeeds, by executeHandler.
+func (e *EventBus) pendingHandlers(ctx context.Context, handlers []EventHandler[any], logger logger.Logger) ([]EventHandler[any], error) {
+	messageID := MessageIDFromContext(ctx)
+	if e.processedMessages == nil || messageID == "" {
+		return handlers, nil
+	}
+
+	pending := make([]EventHandler[any], 0, len(handlers))
+	for _, handler := range handlers {
+		processed, err := e.processedMessages.IsProcessed(ctx, processedScope(e.processedScope, handler.Name), messageID)
+		if err != nil {
+			logger.Error("Failed to check processed message store", map[string]any{
+				"error": err.Error(),
+			})
+			return nil, NewRetryableError(err)
+		}
+		if !processed {
+			pending = append(pending, handler)
+		}
+	}
+	return pending, nil
 }
 
-func (e *EventBus) processPayload(msg *pubsub.Message[any], handler *EventHandler[any], logger logger.Logger) (any, error) {
-	payload := reflect.New(handler.PayloadType).Interface()
+// markProcessed does not fail the message: the handler already ran, and retrying it would repeat its side effects.
+func (e *EventBus) markProcessed(ctx context.Context, handlerName string) {
+	messageID := MessageIDFromContext(ctx)
+	if e.processedMessages == nil || messageID == "" {
+		return
+	}
+
+	if err := e.processedMessages.MarkProcessed(ctx, processedScope(e.processedScope, handlerName), messageID); err != nil {
+		logger.GetLoggerFromContext(ctx).Error("Failed to mark message as processed", map[string]any{
+			"error": err.Error(),
+		})
+	}
+}
+
+// processedScope gives handlers other than the default one a scope of their own.
+func processedScope(scope, handlerName string) string {
+	if handlerName != "" && handlerName != DefaultHandlerName {
+		return scope + ":" + handlerName
+	}
+	return scope
+}
+
+func (e *EventBus) handleUnknownEventType(eventType string, logger logger.Logger) error {
+	switch e.unknownEventTypes {
+	case UnknownEventTypeReject:
+		logger.Error("No handler registered for this event type, rejecting message", map[string]any{
+			"event_type": eventType,
+		})
+		return NewPermanentError(fmt.Errorf("%w: %q", ErrEventTypeNotRegistered, eventType))
+	case UnknownEventTypeWarn:
+		logger.Warn("No handler registered for this event type, ignoring message", map[string]any{
+			"event_type": eventType,
+		})
+	default:
+		logger.Debug("No handler registered for this event type, ignoring message", map[string]any{
+			"event_type": eventType,
+		})
+	}
+	return nil
+}
+
+// recoverPanic turns a panic into a PanicError, so the message is sent to the DLQ instead of crashing the
+// consumer and being redelivered forever.
+func (e *EventBus) recoverPanic(headers pubsub.Headers, recovered any, logger logger.Logger) error {
+	panicErr := &PanicError{Value: recovered, Stack: debug.Stack()}
+
+	logger.Error("Recovered from panic while handling message", map[string]any{
+		"panic":         fmt.Sprint(recovered),
+		"stack":         string(panicErr.Stack),
+		"event_type":    headers.EventType,
+		"event_version": headers.EventVersion,
+		"message_id":    headers.MessageID,
+		"message_key":   headers.Key,
+		"source":        headers.Source,
+	})
+	metrics.HandlerPanics.WithLabelValues(e.topic, e.consumerGroup, headers.EventType).Inc()
+
+	return panicErr
+}
+
+func (e *EventBus) processPayload(ctx context.Context, msg *pubsub.Message[any], payloadType reflect.Type, logger logger.Logger) (any, error) {
+	payload := reflect.New(payloadType).Interface()
+
+	payloadCodec, err := e.codecs.Get(msg.Headers.ContentType)
+	if err != nil {
+		return nil, NewPermanentError(err)
+	}
 
 	dataBytes, err := e.convertToBytes(msg.Payload.Data, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(dataBytes, payload); err != nil {
+	if e.decoding == DecodingStrict {
+		ctx = codec.WithStrictDecoding(ctx)
+	}
+
+	if err := payloadCodec.Decode(ctx, dataBytes, payload); err != nil {
 		logger.Error("Error unmarshalling event payload", map[string]any{
 			"error":        err.Error(),
-			"payload_type": handler.PayloadType.String(),
+			"payload_type": payloadType.String(),
+			"content_type": payloadCodec.ContentType(),
 		})
-		return nil, fmt.Errorf("error unmarshalling event payload for type %s: %w", msg.Headers.EventType, err)
+		err = fmt.Errorf("error unmarshalling event payload for type %s: %w", msg.Headers.EventType, err)
+		if codec.IsTransient(err) {
+			return nil, NewRetryableError(err)
+		}
+		return nil, NewPermanentError(err)
 	}
 
-	if err := e.validatePayloadType(payload, handler, logger); err != nil {
-		return nil, err
-	}
+	return payload, nil
+}
 
+// upcast migrates an older payload version, one version at a time, to the payload of the handler.
+func (e *EventBus) upcast(ctx context.Context, payload any, upcasters []Upcaster, logger logger.Logger) (any, error) {
+	for _, upcaster := range upcasters {
+		upcasted, err := upcaster.Upcast(ctx, payload)
+		if err != nil {
+			err = fmt.Errorf("upcast payload from version %d: %w", upcaster.FromVersion, err)
+			// Upcasters are expected to be pure: unless they classify the failure, it will not go away on retry.
+			var classified classifiedError
+			if !errors.As(err, &classified) {
+				err = NewPermanentError(err)
+			}
+			return nil, err
+		}
+
+		value := reflect.ValueOf(upcasted)
+		if value.Kind() != reflect.Ptr || value.IsNil() {
+			return nil, NewPermanentError(fmt.Errorf("upcast payload from version %d: expected a non-nil pointer, got %T", upcaster.FromVersion, upcasted))
+		}
+
+		logger.Debug("Payload upcast", map[string]any{
+			"from_version": upcaster.FromVersion,
+			"to_version":   upcaster.FromVersion + 1,
+		})
+		payload = upcasted
+	}
 	return payload, nil
 }
 
@@ -91,7 +310,7 @@ func (e *EventBus) convertToBytes(data any, logger logger.Logger) ([]byte, error
 			logger.Error("Error marshalling payload data to JSON", map[string]any{
 				"error": err.Error(),
 			})
-			return nil, fmt.Errorf("error marshalling payload data to JSON: %w", err)
+			return nil, NewPermanentError(fmt.Errorf("error marshalling payload data to JSON: %w", err))
 		}
 		return dataBytes, nil
 	}
@@ -108,22 +327,22 @@ func (e *EventBus) validatePayloadType(payload any, handler *EventHandler[any],
 			"expected_type": handler.PayloadType.String(),
 			"actual_type":   payloadValue.Type().String(),
 		})
-		return fmt.Errorf("payload type mismatch: expected %s, got %s", handler.PayloadType, payloadValue.Type())
+		return NewPermanentError(fmt.Errorf("payload type mismatch: expected %s, got %s", handler.PayloadType, payloadValue.Type()))
 	}
 	return nil
 }
 
-func (e *EventBus) executeHandler(ctx context.Context, handler *EventHandler[any], payload interface{}, logger logger.Logger) error {
-	logger.Debug("Calling event handler")
+func (e *EventBus) executeHandler(ctx context.Context, eventType string, handler *EventHandler[any], payload any) error {
+	ctx = WithHandlerName(ctx, handler.Name)
 
-	err := handler.Handler(ctx, payload)
-	if err != nil {
-		logger.Error("Event handler execution failed", map[string]any{
-			"error": err.Error(),
-		})
+	middlewares := make([]Middleware, 0, len(e.middlewares)+len(e.eventTypeMiddlewares[eventType]))
+	middlewares = append(middlewares, e.middlewares...)
+	middlewares = append(middlewares, e.eventTypeMiddlewares[eventType]...)
+
+	if err := Chain(handler.Handler, middlewares...)(ctx, payload); err != nil {
 		return err
 	}
 
-	logger.Debug("Event handler executed successfully")
+	e.markProcessed(ctx, handler.Name)
 	return nil
 }
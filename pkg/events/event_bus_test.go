@@ -0,0 +1,254 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+type stubProcessedStore map[string]bool
+
+func (s stubProcessedStore) IsProcessed(_ context.Context, scope, messageID string) (bool, error) {
+	return s[scope+"/"+messageID], nil
+}
+
+func (s stubProcessedStore) MarkProcessed(_ context.Context, scope, messageID string) error {
+	s[scope+"/"+messageID] = true
+	return nil
+}
+
+type strictEvent struct {
+	ID string `json:"id"`
+}
+
+func TestEventBusSkipsProcessedMessagesBeforeDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		processed   []string
+		wantErr     bool
+		wantPermErr bool
+	}{
+		{name: "not processed yet", wantErr: true, wantPermErr: true},
+		{name: "processed by every handler", processed: []string{"group/message-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			registry := NewEventHandlerRegistry()
+			err := registry.RegisterHandler("strict.event", func(context.Context, any) error {
+				calls++
+				return nil
+			}, reflect.TypeOf(strictEvent{}))
+			if err != nil {
+				t.Fatalf("register handler: %v", err)
+			}
+
+			store := stubProcessedStore{}
+			for _, key := range tt.processed {
+				store[key] = true
+			}
+			bus := NewEventBus(EventBusDependencies{
+				EventHandlerRegistry:  registry,
+				ProcessedMessageStore: store,
+				ProcessedMessageScope: "group",
+				Decoding:              DecodingStrict,
+			})
+
+			headers := pubsub.NewHeaders("strict.event", "key")
+			headers.MessageID = "message-1"
+			// The unknown field fails strict decoding.
+			msg := pubsub.NewMessage[any](context.Background(), headers, map[string]any{"id": "1", "unknown": true})
+
+			err = bus.Handle(context.Background(), msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantPermErr && !IsPermanent(err) {
+				t.Errorf("Handle() error = %v, want a permanent error", err)
+			}
+			// The handler is never reached: the payload is rejected, or the message skipped.
+			if calls != 0 {
+				t.Errorf("handler called %d times, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestEventBusRecoversFromPanics(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    HandlerFunc
+		middleware Middleware
+		upcast     bool
+	}{
+		{
+			name:    "handler panics",
+			handler: func(context.Context, any) error { panic("handler boom") },
+		},
+		{
+			name: "handler writes to a nil map",
+			handler: func(context.Context, any) error {
+				var attrs map[string]string
+				attrs["name"] = "Ana"
+				return nil
+			},
+		},
+		{
+			name:    "middleware panics",
+			handler: func(context.Context, any) error { return nil },
+			middleware: func(HandlerFunc) HandlerFunc {
+				return func(context.Context, any) error { panic("middleware boom") }
+			},
+		},
+		{
+			name:    "upcaster panics",
+			handler: func(context.Context, any) error { return nil },
+			upcast:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewEventHandlerRegistry()
+			if err := registry.RegisterVersionedHandler("resident.created", 2, tt.handler, reflect.TypeOf(residentV3{})); err != nil {
+				t.Fatalf("register handler: %v", err)
+			}
+			RegisterTypedUpcaster(registry, "resident.created", 1, func(context.Context, *residentV1) (*residentV3, error) {
+				panic("upcaster boom")
+			})
+
+			var middlewares []Middleware
+			if tt.middleware != nil {
+				middlewares = append(middlewares, tt.middleware)
+			}
+			bus := NewEventBus(EventBusDependencies{EventHandlerRegistry: registry, Middlewares: middlewares})
+
+			headers := pubsub.NewHeaders("resident.created", "key")
+			headers.EventVersion = 2
+			if tt.upcast {
+				headers.EventVersion = 1
+			}
+			msg := pubsub.NewMessage[any](context.Background(), headers, map[string]any{"name": "Ana"})
+
+			err := bus.Handle(context.Background(), msg)
+
+			var panicErr *PanicError
+			if !errors.As(err, &panicErr) {
+				t.Fatalf("Handle() error = %v, want a PanicError", err)
+			}
+			if len(panicErr.Stack) == 0 {
+				t.Error("PanicError has no stack")
+			}
+			if !IsPermanent(err) {
+				t.Errorf("Handle() error = %v, want a permanent error", err)
+			}
+		})
+	}
+}
+
+type countingProcessedStore struct {
+	stubProcessedStore
+	lookups int
+}
+
+func (s *countingProcessedStore) IsProcessed(ctx context.Context, scope, messageID string) (bool, error) {
+	s.lookups++
+	return s.stubProcessedStore.IsProcessed(ctx, scope, messageID)
+}
+
+func TestEventBusRunsOnlyPendingHandlers(t *testing.T) {
+	errAudit := errors.New("audit failed")
+
+	tests := []struct {
+		name          string
+		processed     []string
+		auditErr      error
+		wantCalls     []string
+		wantProcessed []string
+	}{
+		{
+			name:          "not processed yet",
+			wantCalls:     []string{"notify", "audit"},
+			wantProcessed: []string{"group:notify/message-1", "group:audit/message-1"},
+		},
+		{
+			name:          "processed by one handler",
+			processed:     []string{"group:notify/message-1"},
+			wantCalls:     []string{"audit"},
+			wantProcessed: []string{"group:notify/message-1", "group:audit/message-1"},
+		},
+		{
+			name:          "processed by every handler",
+			processed:     []string{"group:notify/message-1", "group:audit/message-1"},
+			wantProcessed: []string{"group:notify/message-1", "group:audit/message-1"},
+		},
+		{
+			name:          "failed handler is not recorded",
+			auditErr:      errAudit,
+			wantCalls:     []string{"notify", "audit"},
+			wantProcessed: []string{"group:notify/message-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			registry := NewEventHandlerRegistry()
+			for _, name := range []string{"notify", "audit"} {
+				err := registry.Register(HandlerRegistration{
+					EventType:   "resident.created",
+					Name:        name,
+					PayloadType: reflect.TypeOf(residentV3{}),
+					Handler: func(context.Context, any) error {
+						calls = append(calls, name)
+						if name == "audit" {
+							return tt.auditErr
+						}
+						return nil
+					},
+				})
+				if err != nil {
+					t.Fatalf("register handler %s: %v", name, err)
+				}
+			}
+
+			store := &countingProcessedStore{stubProcessedStore: stubProcessedStore{}}
+			for _, key := range tt.processed {
+				store.stubProcessedStore[key] = true
+			}
+			bus := NewEventBus(EventBusDependencies{
+				EventHandlerRegistry:  registry,
+				ProcessedMessageStore: store,
+				ProcessedMessageScope: "group",
+			})
+
+			headers := pubsub.NewHeaders("resident.created", "key")
+			headers.MessageID = "message-1"
+			msg := pubsub.NewMessage[any](context.Background(), headers, map[string]any{"name": "Ana"})
+
+			err := bus.Handle(context.Background(), msg)
+			if !errors.Is(err, tt.auditErr) || (err != nil) != (tt.auditErr != nil) {
+				t.Fatalf("Handle() error = %v, want %v", err, tt.auditErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("handlers called = %v, want %v", calls, tt.wantCalls)
+			}
+			// The store is checked once per handler, before decoding, and never again by the handlers.
+			if store.lookups != 2 {
+				t.Errorf("IsProcessed called %d times, want 2", store.lookups)
+			}
+
+			want := make(stubProcessedStore)
+			for _, key := range tt.wantProcessed {
+				want[key] = true
+			}
+			if !reflect.DeepEqual(store.stubProcessedStore, want) {
+				t.Errorf("processed = %v, want %v", store.stubProcessedStore, want)
+			}
+		})
+	}
+}
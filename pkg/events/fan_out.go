@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+)
+
+// HandlerResult is the outcome of one of the handlers of an event type.
+type HandlerResult struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// HandlersError reports the results of an event type with several handlers when at least one failed.
+// It is permanent only if every failure is: otherwise the message is retried, and handlers that already
+// succeeded are skipped when a ProcessedMessageStore is configured.
+type HandlersError struct {
+	Results []HandlerResult
+}
+
+func (e *HandlersError) Error() string {
+	failures := make([]string, 0, len(e.Results))
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", result.Name, result.Err))
+		}
+	}
+	return fmt.Sprintf("%d of %d handlers failed: %s", len(failures), len(e.Results), strings.Join(failures, "; "))
+}
+
+func (e *HandlersError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Results))
+	for _, result := range e.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+func (e *HandlersError) Permanent() bool {
+	for _, err := range e.Unwrap() {
+		if !IsPermanent(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// executeHandlers runs every handler of an event type, even when some of them fail or panic. The first handler
+// gets payload and every other one a payload of its own from decode, so handlers running in parallel never
+// share maps, slices or nested pointers.
+func (e *EventBus) executeHandlers(ctx context.Context, eventType string, handlers []EventHandler[any], payload any, decode func() (any, error), logger logger.Logger) error {
+	results := make([]HandlerResult, len(handlers))
+
+	run := func(i int) {
+		handler := &handlers[i]
+		handlerLogger := logger.With("handler", handler.Name)
+		handlerCtx := handlerLogger.AddToContext(ctx, handlerLogger)
+
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				results[i].Err = e.recoverPanic(HeadersFromContext(ctx), recovered, handlerLogger)
+			}
+			results[i].Name = handler.Name
+			results[i].Duration = time.Since(start)
+		}()
+
+		handlerPayload := payload
+		if i > 0 {
+			decoded, err := decode()
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			handlerPayload = decoded
+		}
+		results[i].Err = e.executeHandler(handlerCtx, eventType, handler, handlerPayload)
+	}
+
+	mode := e.eventHandlerRegistry.GetExecutionMode(eventType)
+	if mode == ExecutionParallel {
+		var wg sync.WaitGroup
+		for i := range handlers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range handlers {
+			run(i)
+		}
+	}
+
+	outcomes := make(map[string]string, len(results))
+	failed := false
+	for _, result := range results {
+		outcomes[result.Name] = "ok"
+		if result.Err != nil {
+			outcomes[result.Name] = result.Err.Error()
+			failed = true
+		}
+	}
+	logger.Info("Event handlers finished", map[string]any{
+		"execution_mode": string(mode),
+		"results":        outcomes,
+	})
+
+	if failed {
+		return &HandlersError{Results: results}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+type fanOutEvent struct {
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+func TestFanOutHandlersGetTheirOwnPayload(t *testing.T) {
+	for _, mode := range []ExecutionMode{ExecutionSequential, ExecutionParallel} {
+		t.Run(string(mode), func(t *testing.T) {
+			registry := NewEventHandlerRegistry()
+			registry.SetExecutionMode("fan_out.event", mode)
+
+			seen := make(chan fanOutEvent, 3)
+			for _, name := range []string{"first", "second", "third"} {
+				err := registry.Register(HandlerRegistration{
+					EventType:   "fan_out.event",
+					Name:        name,
+					PayloadType: reflect.TypeOf(fanOutEvent{}),
+					Handler: func(_ context.Context, payload any) error {
+						event := payload.(*fanOutEvent)
+						// Handlers mutating nested values must not affect each other.
+						event.Tags = append(event.Tags[:0], name)
+						event.Attrs["handler"] = name
+						seen <- fanOutEvent{Tags: append([]string(nil), event.Tags...), Attrs: map[string]string{"original": event.Attrs["original"]}}
+						return nil
+					},
+				})
+				if err != nil {
+					t.Fatalf("register handler %s: %v", name, err)
+				}
+			}
+
+			bus := NewEventBus(EventBusDependencies{EventHandlerRegistry: registry})
+			msg := pubsub.NewMessage[any](context.Background(), pubsub.NewHeaders("fan_out.event", "key"), map[string]any{
+				"tags":  []string{"original"},
+				"attrs": map[string]string{"original": "value"},
+			})
+
+			if err := bus.Handle(context.Background(), msg); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			close(seen)
+
+			for event := range seen {
+				if len(event.Tags) != 1 || event.Attrs["original"] != "value" {
+					t.Errorf("handler saw a payload modified by another handler: %+v", event)
+				}
+			}
+		})
+	}
+}
+
+func TestFanOutResults(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	tests := []struct {
+		name          string
+		outcomes      map[string]error
+		wantFailed    []string
+		wantPermanent bool
+	}{
+		{
+			name:     "every handler succeeds",
+			outcomes: map[string]error{"first": nil, "second": nil, "third": nil},
+		},
+		{
+			name:       "one retryable failure",
+			outcomes:   map[string]error{"first": nil, "second": errTransient, "third": nil},
+			wantFailed: []string{"second"},
+		},
+		{
+			name:          "every failure permanent",
+			outcomes:      map[string]error{"first": NewPermanentError(errTransient), "second": nil, "third": &PanicError{}},
+			wantFailed:    []string{"first", "third"},
+			wantPermanent: true,
+		},
+		{
+			name:       "permanent and retryable failures",
+			outcomes:   map[string]error{"first": NewPermanentError(errTransient), "second": errTransient, "third": nil},
+			wantFailed: []string{"first", "second"},
+		},
+	}
+
+	for _, mode := range []ExecutionMode{ExecutionSequential, ExecutionParallel} {
+		for _, tt := range tests {
+			t.Run(string(mode)+"/"+tt.name, func(t *testing.T) {
+				registry := NewEventHandlerRegistry()
+				registry.SetExecutionMode("fan_out.event", mode)
+
+				var mu sync.Mutex
+				called := make(map[string]bool)
+				for _, name := range []string{"first", "second", "third"} {
+					err := registry.Register(HandlerRegistration{
+						EventType:   "fan_out.event",
+						Name:        name,
+						PayloadType: reflect.TypeOf(fanOutEvent{}),
+						Handler: func(context.Context, any) error {
+							mu.Lock()
+							called[name] = true
+							mu.Unlock()
+
+							// A PanicError outcome stands for a handler that panics.
+							var panicErr *PanicError
+							if errors.As(tt.outcomes[name], &panicErr) {
+								panic(name + " boom")
+							}
+							return tt.outcomes[name]
+						},
+					})
+					if err != nil {
+						t.Fatalf("register handler %s: %v", name, err)
+					}
+				}
+
+				bus := NewEventBus(EventBusDependencies{EventHandlerRegistry: registry})
+				msg := pubsub.NewMessage[any](context.Background(), pubsub.NewHeaders("fan_out.event", "key"), map[string]any{})
+				err := bus.Handle(context.Background(), msg)
+
+				// Every handler runs, even after another one failed or panicked.
+				if len(called) != len(tt.outcomes) {
+					t.Errorf("handlers called = %v, want all of %d", called, len(tt.outcomes))
+				}
+
+				if tt.wantFailed == nil {
+					if err != nil {
+						t.Fatalf("Handle() error = %v, want nil", err)
+					}
+					return
+				}
+
+				var handlersErr *HandlersError
+				if !errors.As(err, &handlersErr) {
+					t.Fatalf("Handle() error = %v, want a HandlersError", err)
+				}
+				var failed []string
+				for _, result := range handlersErr.Results {
+					if result.Err != nil {
+						failed = append(failed, result.Name)
+					}
+				}
+				if !reflect.DeepEqual(failed, tt.wantFailed) {
+					t.Errorf("failed handlers = %v, want %v", failed, tt.wantFailed)
+				}
+				if IsPermanent(err) != tt.wantPermanent {
+					t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.wantPermanent)
+				}
+			})
+		}
+	}
+}
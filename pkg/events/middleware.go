@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrSourceNotAllowed = errors.New("message source not allowed")
+
+// HandlerFunc handles a decoded payload. Registered handlers and middleware chains share this shape.
+type HandlerFunc func(ctx context.Context, payload any) error
+
+// Middleware wraps a handler with cross-cutting behavior. The headers of the message being handled are
+// available through HeadersFromContext.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps handler with middlewares, the first one being the outermost.
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Tracing records a span per handler call.
+func Tracing() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) (err error) {
+			ctx, span := tracing.Start(ctx, "handle "+HeadersFromContext(ctx).EventType,
+				trace.WithAttributes(
+					attribute.String("event.payload_type", payloadTypeName(payload)),
+					attribute.String("event.handler", HandlerNameFromContext(ctx)),
+				),
+			)
+			defer func() { tracing.End(span, err) }()
+
+			return next(ctx, payload)
+		}
+	}
+}
+
+// Timing records the handler duration, labelled with the topic and consumer group of the bus.
+func Timing(topic, consumerGroup string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) (err error) {
+			start := time.Now()
+			defer func() {
+				metrics.HandlerDuration.WithLabelValues(
+					topic,
+					consumerGroup,
+					HeadersFromContext(ctx).EventType,
+					HandlerNameFromContext(ctx),
+					metrics.Result(err),
+				).Observe(time.Since(start).Seconds())
+			}()
+
+			return next(ctx, payload)
+		}
+	}
+}
+
+func Logging() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) error {
+			logger := logger.GetLoggerFromContext(ctx)
+			logger.Debug("Calling event handler")
+
+			if err := next(ctx, payload); err != nil {
+				logger.Error("Event handler execution failed", map[string]any{
+					"error":     err.Error(),
+					"permanent": IsPermanent(err),
+				})
+				return err
+			}
+
+			logger.Debug("Event handler executed successfully")
+			return nil
+		}
+	}
+}
+
+// AllowSources rejects, as a permanent failure, messages whose Source header is not one of sources.
+func AllowSources(sources ...string) Middleware {
+	allowed := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		allowed[source] = true
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) error {
+			source := HeadersFromContext(ctx).Source
+			if !allowed[source] {
+				logger.GetLoggerFromContext(ctx).Warn("Message source not allowed", map[string]any{
+					"source": source,
+				})
+				return NewPermanentError(fmt.Errorf("%w: %q", ErrSourceNotAllowed, source))
+			}
+
+			return next(ctx, payload)
+		}
+	}
+}
+
+// Transactional runs the handler inside the transaction started by withTransaction, so its domain writes
+// and the messages it publishes through the outbox are committed together.
+func Transactional(withTransaction func(ctx context.Context, fn func(ctx context.Context) error) error) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) error {
+			return withTransaction(ctx, func(ctx context.Context) error {
+				return next(ctx, payload)
+			})
+		}
+	}
+}
+
+func payloadTypeName(payload any) string {
+	payloadType := reflect.TypeOf(payload)
+	if payloadType == nil {
+		return ""
+	}
+	if payloadType.Kind() == reflect.Ptr {
+		payloadType = payloadType.Elem()
+	}
+	return payloadType.String()
+}
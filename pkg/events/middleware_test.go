@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, payload any) error {
+			*calls = append(*calls, "before "+name)
+			err := next(ctx, payload)
+			*calls = append(*calls, "after "+name)
+			return err
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		middlewares []string
+		want        []string
+	}{
+		{name: "no middlewares", want: []string{"handler"}},
+		{name: "one middleware", middlewares: []string{"a"}, want: []string{"before a", "handler", "after a"}},
+		{
+			name:        "first middleware is the outermost",
+			middlewares: []string{"a", "b", "c"},
+			want:        []string{"before a", "before b", "before c", "handler", "after c", "after b", "after a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			middlewares := make([]Middleware, 0, len(tt.middlewares))
+			for _, name := range tt.middlewares {
+				middlewares = append(middlewares, recordingMiddleware(name, &calls))
+			}
+
+			handler := Chain(func(context.Context, any) error {
+				calls = append(calls, "handler")
+				return nil
+			}, middlewares...)
+
+			if err := handler(context.Background(), nil); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventBusMiddlewareOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventType string
+		want      []string
+	}{
+		{
+			name:      "bus middlewares wrap event type middlewares",
+			eventType: "resident.created",
+			want:      []string{"before bus", "before event type", "handler", "after event type", "after bus"},
+		},
+		{
+			name:      "event type middlewares only wrap their event type",
+			eventType: "resident.moved",
+			want:      []string{"before bus", "handler", "after bus"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			registry := NewEventHandlerRegistry()
+			for _, eventType := range []string{"resident.created", "resident.moved"} {
+				err := registry.RegisterHandler(eventType, func(context.Context, any) error {
+					calls = append(calls, "handler")
+					return nil
+				}, reflect.TypeOf(residentV3{}))
+				if err != nil {
+					t.Fatalf("register %s: %v", eventType, err)
+				}
+			}
+
+			bus := NewEventBus(EventBusDependencies{
+				EventHandlerRegistry: registry,
+				Middlewares:          []Middleware{recordingMiddleware("bus", &calls)},
+				EventTypeMiddlewares: map[string][]Middleware{
+					"resident.created": {recordingMiddleware("event type", &calls)},
+				},
+			})
+
+			msg := pubsub.NewMessage[any](context.Background(), pubsub.NewHeaders(tt.eventType, "key"), map[string]any{"name": "Ana"})
+			if err := bus.Handle(context.Background(), msg); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("calls = %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
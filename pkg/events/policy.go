@@ -0,0 +1,24 @@
+package events
+
+// UnknownEventTypePolicy decides what the bus does with messages whose event type has no handler.
+type UnknownEventTypePolicy string
+
+const (
+	// UnknownEventTypeIgnore acknowledges the message, logging it at debug level. It is the default.
+	UnknownEventTypeIgnore UnknownEventTypePolicy = "ignore"
+	// UnknownEventTypeWarn acknowledges the message, logging it as a warning.
+	UnknownEventTypeWarn UnknownEventTypePolicy = "warn"
+	// UnknownEventTypeReject fails the message with a permanent error, so it is dead-lettered.
+	UnknownEventTypeReject UnknownEventTypePolicy = "reject"
+)
+
+// DecodingMode decides whether payload fields unknown to the handler's payload type are accepted.
+type DecodingMode string
+
+const (
+	// DecodingLenient ignores unknown fields. It is the default.
+	DecodingLenient DecodingMode = "lenient"
+	// DecodingStrict fails, with a permanent error, payloads carrying unknown fields. Avro payloads are
+	// exempt: their evolution is governed by the schema registry compatibility rules.
+	DecodingStrict DecodingMode = "strict"
+)
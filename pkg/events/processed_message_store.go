@@ -0,0 +1,10 @@
+package events
+
+import "context"
+
+// ProcessedMessageStore remembers which messages were already handled so redeliveries are skipped.
+// Scope isolates the bookkeeping of different consumers of the same message.
+type ProcessedMessageStore interface {
+	IsProcessed(ctx context.Context, scope, messageID string) (bool, error)
+	MarkProcessed(ctx context.Context, scope, messageID string) error
+}
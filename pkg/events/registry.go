@@ -2,38 +2,189 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 )
 
+// DefaultEventVersion is assumed for messages without a version header and for handlers registered
+// through RegisterHandler.
+const DefaultEventVersion = 1
+
+// DefaultHandlerName names handlers registered without a name.
+const DefaultHandlerName = "default"
+
+type ExecutionMode string
+
+const (
+	// ExecutionSequential runs the handlers of an event type one after the other, in registration order.
+	ExecutionSequential ExecutionMode = "sequential"
+	ExecutionParallel   ExecutionMode = "parallel"
+)
+
+var (
+	ErrEventTypeNotRegistered  = errors.New("event handler not registered for event type")
+	ErrUnsupportedEventVersion = errors.New("unsupported event version")
+	ErrDuplicateHandler        = errors.New("event handler already registered")
+)
+
 type EventHandlerRegistry struct {
-	EventHandlers map[string]EventHandler[any]
+	// EventHandlers holds the handlers of the latest version of each event type.
+	EventHandlers  map[string][]EventHandler[any]
+	versions       map[string]map[int][]EventHandler[any]
+	upcasters      map[string]map[int]Upcaster
+	executionModes map[string]ExecutionMode
 }
 
 type EventHandler[T any] struct {
+	Name        string
 	Handler     func(ctx context.Context, payload T) error
 	PayloadType reflect.Type
+	Version     int
+}
+
+// HandlerRegistration describes a handler of one version of an event type. Handlers of the same event type
+// and version must share the payload type, and are told apart by their name.
+type HandlerRegistration struct {
+	EventType   string
+	Version     int
+	Name        string
+	Handler     func(ctx context.Context, payload any) error
+	PayloadType reflect.Type
+}
+
+// Upcaster turns a payload of FromVersion, decoded into PayloadType, into a pointer to the payload of the
+// next version.
+type Upcaster struct {
+	FromVersion int
+	PayloadType reflect.Type
+	Upcast      func(ctx context.Context, payload any) (any, error)
 }
 
 func NewEventHandlerRegistry() *EventHandlerRegistry {
 	return &EventHandlerRegistry{
-		EventHandlers: make(map[string]EventHandler[any]),
+		EventHandlers:  make(map[string][]EventHandler[any]),
+		versions:       make(map[string]map[int][]EventHandler[any]),
+		upcasters:      make(map[string]map[int]Upcaster),
+		executionModes: make(map[string]ExecutionMode),
 	}
 }
 
-func (e *EventHandlerRegistry) RegisterHandler(eventType string, handler func(ctx context.Context, payload any) error, payloadType reflect.Type) {
-	e.EventHandlers[eventType] = EventHandler[any]{
+func (e *EventHandlerRegistry) RegisterHandler(eventType string, handler func(ctx context.Context, payload any) error, payloadType reflect.Type) error {
+	return e.Register(HandlerRegistration{
+		EventType:   eventType,
+		Handler:     handler,
+		PayloadType: payloadType,
+	})
+}
+
+// RegisterVersionedHandler registers the handler of one version of eventType. Messages of a version without
+// its own handlers are upcast to the latest registered version.
+func (e *EventHandlerRegistry) RegisterVersionedHandler(eventType string, version int, handler func(ctx context.Context, payload any) error, payloadType reflect.Type) error {
+	return e.Register(HandlerRegistration{
+		EventType:   eventType,
+		Version:     version,
 		Handler:     handler,
 		PayloadType: payloadType,
+	})
+}
+
+// Register adds a handler; the version defaults to DefaultEventVersion and the name to DefaultHandlerName.
+func (e *EventHandlerRegistry) Register(registration HandlerRegistration) error {
+	if registration.Version == 0 {
+		registration.Version = DefaultEventVersion
+	}
+	if registration.Name == "" {
+		registration.Name = DefaultHandlerName
+	}
+
+	handlers := e.versions[registration.EventType][registration.Version]
+	for _, handler := range handlers {
+		if handler.Name == registration.Name {
+			return fmt.Errorf("%w: %s version %d handler %q", ErrDuplicateHandler, registration.EventType, registration.Version, registration.Name)
+		}
+		if handler.PayloadType != registration.PayloadType {
+			return fmt.Errorf("handler %q of %s version %d expects %s, but the registered handlers expect %s",
+				registration.Name, registration.EventType, registration.Version, registration.PayloadType, handler.PayloadType)
+		}
+	}
+
+	handlers = append(handlers, EventHandler[any]{
+		Name:        registration.Name,
+		Handler:     registration.Handler,
+		PayloadType: registration.PayloadType,
+		Version:     registration.Version,
+	})
+
+	if e.versions[registration.EventType] == nil {
+		e.versions[registration.EventType] = make(map[int][]EventHandler[any])
+	}
+	e.versions[registration.EventType][registration.Version] = handlers
+
+	if latest, ok := e.EventHandlers[registration.EventType]; !ok || registration.Version >= latest[0].Version {
+		e.EventHandlers[registration.EventType] = handlers
+	}
+	return nil
+}
+
+// RegisterUpcaster registers the migration of eventType payloads from fromVersion to fromVersion+1.
+func (e *EventHandlerRegistry) RegisterUpcaster(eventType string, fromVersion int, payloadType reflect.Type, upcast func(ctx context.Context, payload any) (any, error)) {
+	if e.upcasters[eventType] == nil {
+		e.upcasters[eventType] = make(map[int]Upcaster)
+	}
+	e.upcasters[eventType][fromVersion] = Upcaster{
+		FromVersion: fromVersion,
+		PayloadType: payloadType,
+		Upcast:      upcast,
+	}
+}
+
+// SetExecutionMode sets how the handlers of eventType run; ExecutionSequential is the default.
+func (e *EventHandlerRegistry) SetExecutionMode(eventType string, mode ExecutionMode) {
+	e.executionModes[eventType] = mode
+}
+
+func (e *EventHandlerRegistry) GetExecutionMode(eventType string) ExecutionMode {
+	if mode, ok := e.executionModes[eventType]; ok {
+		return mode
 	}
+	return ExecutionSequential
 }
 
-func (e *EventHandlerRegistry) GetEventHandlerByEventType(eventType string) (*EventHandler[any], error) {
-	eventHandler, ok := e.EventHandlers[eventType]
+func (e *EventHandlerRegistry) GetEventHandlersByEventType(eventType string) ([]EventHandler[any], error) {
+	eventHandlers, ok := e.EventHandlers[eventType]
 	if !ok {
-		return nil, fmt.Errorf("event handler not registered for event type: %s", eventType)
+		return nil, fmt.Errorf("%w: %s", ErrEventTypeNotRegistered, eventType)
+	}
+	return eventHandlers, nil
+}
+
+// Resolve returns the handlers for a message of eventType at version, and the upcasters to apply, in order,
+// to a payload decoded into the first upcaster's PayloadType. No upcasters are returned when the version has
+// its own handlers.
+func (e *EventHandlerRegistry) Resolve(eventType string, version int) ([]EventHandler[any], []Upcaster, error) {
+	latest, err := e.GetEventHandlersByEventType(eventType)
+	if err != nil {
+		return nil, nil, err
+	}
+	latestVersion := latest[0].Version
+
+	if eventHandlers, ok := e.versions[eventType][version]; ok {
+		return eventHandlers, nil, nil
 	}
-	return &eventHandler, nil
+	if version > latestVersion {
+		return nil, nil, fmt.Errorf("%w: %s version %d is newer than the latest handled version %d", ErrUnsupportedEventVersion, eventType, version, latestVersion)
+	}
+
+	upcasters := make([]Upcaster, 0, latestVersion-version)
+	for from := version; from < latestVersion; from++ {
+		upcaster, ok := e.upcasters[eventType][from]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: no upcaster for %s from version %d", ErrUnsupportedEventVersion, eventType, from)
+		}
+		upcasters = append(upcasters, upcaster)
+	}
+	return latest, upcasters, nil
 }
 
 func (e *EventHandlerRegistry) GetAllEventTypes() []string {
@@ -43,3 +194,17 @@ func (e *EventHandlerRegistry) GetAllEventTypes() []string {
 	}
 	return eventTypes
 }
+
+// RegisterTypedUpcaster registers an upcaster from the From payload to the To payload of the next version.
+func RegisterTypedUpcaster[From, To any](registry *EventHandlerRegistry, eventType string, fromVersion int, upcast func(ctx context.Context, payload *From) (*To, error)) {
+	var zero From
+
+	registry.RegisterUpcaster(
+		eventType,
+		fromVersion,
+		reflect.TypeOf(zero),
+		func(ctx context.Context, payload any) (any, error) {
+			return upcast(ctx, payload.(*From))
+		},
+	)
+}
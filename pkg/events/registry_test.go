@@ -0,0 +1,248 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+)
+
+type residentV1 struct {
+	FullName string `json:"full_name"`
+}
+
+type residentV2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+type residentV3 struct {
+	Name string `json:"name"`
+}
+
+func TestEventHandlerRegistryRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		registrations []HandlerRegistration
+		wantErr       bool
+		wantDup       bool
+	}{
+		{
+			name: "handlers of several versions",
+			registrations: []HandlerRegistration{
+				{EventType: "resident.created", PayloadType: reflect.TypeOf(residentV1{})},
+				{EventType: "resident.created", Version: 2, PayloadType: reflect.TypeOf(residentV2{})},
+			},
+		},
+		{
+			name: "named handlers of one version",
+			registrations: []HandlerRegistration{
+				{EventType: "resident.created", Name: "notify", PayloadType: reflect.TypeOf(residentV1{})},
+				{EventType: "resident.created", Name: "audit", PayloadType: reflect.TypeOf(residentV1{})},
+			},
+		},
+		{
+			name: "duplicate handler name",
+			registrations: []HandlerRegistration{
+				{EventType: "resident.created", PayloadType: reflect.TypeOf(residentV1{})},
+				{EventType: "resident.created", Version: DefaultEventVersion, PayloadType: reflect.TypeOf(residentV1{})},
+			},
+			wantErr: true,
+			wantDup: true,
+		},
+		{
+			name: "payload type differs from the version's handlers",
+			registrations: []HandlerRegistration{
+				{EventType: "resident.created", Name: "notify", PayloadType: reflect.TypeOf(residentV1{})},
+				{EventType: "resident.created", Name: "audit", PayloadType: reflect.TypeOf(residentV2{})},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewEventHandlerRegistry()
+
+			var err error
+			for _, registration := range tt.registrations {
+				registration.Handler = func(context.Context, any) error { return nil }
+				if err = registry.Register(registration); err != nil {
+					break
+				}
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrDuplicateHandler) != tt.wantDup {
+				t.Errorf("Register() error = %v, want ErrDuplicateHandler %v", err, tt.wantDup)
+			}
+		})
+	}
+}
+
+func TestEventHandlerRegistryResolve(t *testing.T) {
+	newRegistry := func(t *testing.T) *EventHandlerRegistry {
+		t.Helper()
+
+		registry := NewEventHandlerRegistry()
+		noop := func(context.Context, any) error { return nil }
+		for version, payloadType := range map[int]reflect.Type{
+			1: reflect.TypeOf(residentV1{}),
+			3: reflect.TypeOf(residentV3{}),
+		} {
+			if err := registry.RegisterVersionedHandler("resident.created", version, noop, payloadType); err != nil {
+				t.Fatalf("register version %d: %v", version, err)
+			}
+		}
+		if err := registry.RegisterHandler("resident.moved", noop, reflect.TypeOf(residentV3{})); err != nil {
+			t.Fatalf("register resident.moved: %v", err)
+		}
+
+		RegisterTypedUpcaster(registry, "resident.created", 2, func(_ context.Context, payload *residentV2) (*residentV3, error) {
+			return &residentV3{Name: payload.FirstName + " " + payload.LastName}, nil
+		})
+		return registry
+	}
+
+	tests := []struct {
+		name          string
+		eventType     string
+		version       int
+		wantVersion   int
+		wantUpcasters []int
+		wantErr       error
+	}{
+		{name: "latest version", eventType: "resident.created", version: 3, wantVersion: 3},
+		{name: "older version with its own handlers", eventType: "resident.created", version: 1, wantVersion: 1},
+		{name: "older version upcast to the latest", eventType: "resident.created", version: 2, wantVersion: 3, wantUpcasters: []int{2}},
+		{name: "newer than the latest version", eventType: "resident.created", version: 4, wantErr: ErrUnsupportedEventVersion},
+		{name: "missing upcaster", eventType: "resident.moved", version: 0, wantErr: ErrUnsupportedEventVersion},
+		{name: "unregistered event type", eventType: "resident.deleted", version: 1, wantErr: ErrEventTypeNotRegistered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, upcasters, err := newRegistry(t).Resolve(tt.eventType, tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			if len(handlers) != 1 || handlers[0].Version != tt.wantVersion {
+				t.Errorf("Resolve() handlers = %+v, want the version %d handler", handlers, tt.wantVersion)
+			}
+
+			var fromVersions []int
+			for _, upcaster := range upcasters {
+				fromVersions = append(fromVersions, upcaster.FromVersion)
+			}
+			if !reflect.DeepEqual(fromVersions, tt.wantUpcasters) {
+				t.Errorf("Resolve() upcasters from versions %v, want %v", fromVersions, tt.wantUpcasters)
+			}
+			if len(upcasters) > 0 && upcasters[0].PayloadType != reflect.TypeOf(residentV2{}) {
+				t.Errorf("first upcaster payload type = %s, want %s", upcasters[0].PayloadType, reflect.TypeOf(residentV2{}))
+			}
+		})
+	}
+}
+
+func TestEventBusUpcastsOlderVersions(t *testing.T) {
+	errUpcast := errors.New("upcast failed")
+
+	tests := []struct {
+		name          string
+		version       int
+		payload       map[string]any
+		upcastErr     error
+		want          residentV3
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{
+			name:    "latest version",
+			version: 3,
+			payload: map[string]any{"name": "Ana Souza"},
+			want:    residentV3{Name: "Ana Souza"},
+		},
+		{
+			name:    "upcast through every version",
+			version: 1,
+			payload: map[string]any{"full_name": "Ana Souza"},
+			want:    residentV3{Name: "Ana Souza"},
+		},
+		{
+			name:    "unversioned message is version one",
+			payload: map[string]any{"full_name": "Ana Souza"},
+			want:    residentV3{Name: "Ana Souza"},
+		},
+		{
+			name:          "unclassified upcaster failure",
+			version:       2,
+			payload:       map[string]any{"first_name": "Ana"},
+			upcastErr:     errUpcast,
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:      "retryable upcaster failure",
+			version:   2,
+			payload:   map[string]any{"first_name": "Ana"},
+			upcastErr: NewRetryableError(errUpcast),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *residentV3
+			registry := NewEventHandlerRegistry()
+			err := registry.RegisterVersionedHandler("resident.created", 3, func(_ context.Context, payload any) error {
+				got = payload.(*residentV3)
+				return nil
+			}, reflect.TypeOf(residentV3{}))
+			if err != nil {
+				t.Fatalf("register handler: %v", err)
+			}
+
+			RegisterTypedUpcaster(registry, "resident.created", 1, func(_ context.Context, payload *residentV1) (*residentV2, error) {
+				first, last, _ := strings.Cut(payload.FullName, " ")
+				return &residentV2{FirstName: first, LastName: last}, nil
+			})
+			RegisterTypedUpcaster(registry, "resident.created", 2, func(_ context.Context, payload *residentV2) (*residentV3, error) {
+				if tt.upcastErr != nil {
+					return nil, tt.upcastErr
+				}
+				return &residentV3{Name: payload.FirstName + " " + payload.LastName}, nil
+			})
+
+			headers := pubsub.NewHeaders("resident.created", "key")
+			headers.EventVersion = tt.version
+			msg := pubsub.NewMessage[any](context.Background(), headers, tt.payload)
+
+			err = NewEventBus(EventBusDependencies{EventHandlerRegistry: registry}).Handle(context.Background(), msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, errUpcast) || IsPermanent(err) != tt.wantPermanent {
+					t.Errorf("Handle() error = %v, want it to wrap %v with permanent %v", err, errUpcast, tt.wantPermanent)
+				}
+				return
+			}
+
+			if got == nil || *got != tt.want {
+				t.Errorf("handler payload = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	validator "github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a payload field that failed one of its validate rules. Field is the path of the
+// field as it appears in the payload, such as "address.street".
+type FieldError struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+func (e FieldError) String() string {
+	if e.Param != "" {
+		return fmt.Sprintf("%s failed %s=%s", e.Field, e.Rule, e.Param)
+	}
+	return fmt.Sprintf("%s failed %s", e.Field, e.Rule)
+}
+
+// ValidationError is returned when a payload does not satisfy the validate tags of its type. It is
+// permanent: the same payload fails again on redelivery.
+type ValidationError struct {
+	PayloadType string
+	Fields      []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, field.String())
+	}
+	return fmt.Sprintf("invalid %s payload: %s", e.PayloadType, strings.Join(fields, "; "))
+}
+
+func (e *ValidationError) Permanent() bool { return true }
+
+// newPayloadValidator reports fields by their JSON name, so errors match the payload on the wire.
+func newPayloadValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
+func validatePayload(validate *validator.Validate, payload any) error {
+	payloadType := reflect.TypeOf(payload)
+	if payloadType == nil {
+		return nil
+	}
+	if payloadType.Kind() == reflect.Ptr {
+		payloadType = payloadType.Elem()
+	}
+	if payloadType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := validate.Struct(payload)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return NewPermanentError(fmt.Errorf("validate %s payload: %w", payloadType, err))
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		// The namespace starts with the payload type name.
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		fields = append(fields, FieldError{
+			Field: field,
+			Rule:  fieldErr.Tag(),
+			Param: fieldErr.Param(),
+		})
+	}
+	return &ValidationError{PayloadType: payloadType.String(), Fields: fields}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type validatedAddress struct {
+	Street string `json:"street" validate:"required"`
+}
+
+type validatedResident struct {
+	Name    string           `json:"name" validate:"required"`
+	Phone   string           `json:"phone,omitempty" validate:"omitempty,e164"`
+	Floor   int              `json:"floor" validate:"gte=0,lte=40"`
+	Address validatedAddress `json:"address"`
+	Note    string           `validate:"max=5"`
+}
+
+func TestValidatePayload(t *testing.T) {
+	valid := validatedResident{Name: "Ana", Phone: "+5511999999999", Floor: 3, Address: validatedAddress{Street: "Rua A"}}
+
+	tests := []struct {
+		name       string
+		payload    any
+		wantFields []FieldError
+	}{
+		{name: "valid", payload: &valid},
+		{name: "not a struct", payload: map[string]any{"name": ""}},
+		{name: "nil", payload: nil},
+		{
+			name:       "missing required field",
+			payload:    &validatedResident{Phone: valid.Phone, Floor: 3, Address: valid.Address},
+			wantFields: []FieldError{{Field: "name", Rule: "required"}},
+		},
+		{
+			name: "several fields",
+			payload: &validatedResident{
+				Name:  "Ana",
+				Phone: "not a phone",
+				Floor: 41,
+				Note:  "too long",
+			},
+			wantFields: []FieldError{
+				{Field: "phone", Rule: "e164"},
+				{Field: "floor", Rule: "lte", Param: "40"},
+				{Field: "address.street", Rule: "required"},
+				{Field: "Note", Rule: "max", Param: "5"},
+			},
+		},
+	}
+
+	validate := newPayloadValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePayload(validate, tt.payload)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("validatePayload() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("validatePayload() error = %v, want a ValidationError", err)
+			}
+			if validationErr.PayloadType != "events.validatedResident" {
+				t.Errorf("PayloadType = %q, want %q", validationErr.PayloadType, "events.validatedResident")
+			}
+			if !reflect.DeepEqual(validationErr.Fields, tt.wantFields) {
+				t.Errorf("Fields = %+v, want %+v", validationErr.Fields, tt.wantFields)
+			}
+			if !IsPermanent(err) {
+				t.Errorf("validatePayload() error = %v, want a permanent error", err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{
+		PayloadType: "events.validatedResident",
+		Fields: []FieldError{
+			{Field: "name", Rule: "required"},
+			{Field: "floor", Rule: "lte", Param: "40"},
+		},
+	}
+
+	want := "invalid events.validatedResident payload: name failed required; floor failed lte=40"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
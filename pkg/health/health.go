@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "unavailable"
+)
+
+// Reporter is implemented by components that can report their own health.
+type Reporter interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Check returns nil when the dependency it probes is healthy.
+type Check func(ctx context.Context) error
+
+// Checker runs a named set of checks concurrently, each bounded by the same timeout.
+type Checker struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  map[string]Check
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: make(map[string]Check)}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for index, name := range names {
+		checks[index] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for index, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[index] = check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]string, len(names))}
+	for index, name := range names {
+		if results[index] != nil {
+			report.Status = StatusDegraded
+			report.Checks[name] = results[index].Error()
+			continue
+		}
+		report.Checks[name] = StatusOK
+	}
+	return report
+}
+
+// Handler answers 200 when every check passes and 503 otherwise, with the report as JSON.
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
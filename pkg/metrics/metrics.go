@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "entregador"
+
+var messageLabels = []string{"topic", "consumer_group", "event_type"}
+
+// Registry holds every collector exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	MessagesReceived = newCounter("messages_received_total", "Messages read from a subscription.", messageLabels)
+	MessagesHandled  = newCounter("messages_handled_total", "Messages processed and acknowledged.", messageLabels)
+	MessagesFailed   = newCounter("messages_failed_total", "Messages that failed and were nacked for redelivery.", messageLabels)
+	MessagesRetried  = newCounter("messages_retried_total", "Handler attempts made after a failed one.", messageLabels)
+
+	MessagesDeadLettered = newCounter("messages_dead_lettered_total", "Messages published to the DLQ.",
+		append(messageLabels, "failure_reason"))
+	UnhandledEventTypes = newCounter("unhandled_event_types_total", "Messages whose event type has no registered handler.",
+		messageLabels)
+
+	HandlerPanics = newCounter("handler_panics_total", "Messages whose handling panicked.",
+		messageLabels)
+
+	HandlerDuration = newHistogram("handler_duration_seconds", "Time spent in an event handler.",
+		append(messageLabels, "handler", "result"))
+	PublishDuration = newHistogram("publish_duration_seconds", "Time spent publishing a batch of messages.",
+		[]string{"topic", "result"})
+	ConsumerRebalances = newCounter("consumer_rebalances_total", "Consumer group sessions started after a rebalance.",
+		[]string{"topic", "consumer_group"})
+
+	CircuitBreakerTransitions = newCounter("circuit_breaker_transitions_total", "Circuit breaker state changes.",
+		[]string{"name", "state"})
+	CircuitBreakerRejected = newCounter("circuit_breaker_rejected_total", "Calls rejected by an open circuit breaker.",
+		[]string{"name"})
+	CircuitBreakerState = newGauge("circuit_breaker_state", "Circuit breaker state: 0 closed, 1 half-open, 2 open.",
+		[]string{"name"})
+
+	MongoOperationDuration = newHistogram("mongo_operation_duration_seconds", "Time spent in a MongoDB collection operation.",
+		[]string{"collection", "operation", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MustRegister adds collectors, such as the ones computing values at scrape time, to Registry.
+func MustRegister(collectors ...prometheus.Collector) {
+	Registry.MustRegister(collectors...)
+}
+
+// NewDesc describes a metric in the application namespace for custom collectors.
+func NewDesc(name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Result is the result label value for an operation that returned err.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+func newCounter(name, help string, labels []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	Registry.MustRegister(counter)
+	return counter
+}
+
+func newGauge(name, help string, labels []string) *prometheus.GaugeVec {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	Registry.MustRegister(gauge)
+	return gauge
+}
+
+func newHistogram(name, help string, labels []string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   prometheus.DefBuckets,
+	}, labels)
+	Registry.MustRegister(histogram)
+	return histogram
+}
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+)
+
+type contextKey string
+
+const attemptContextKey contextKey = "retry_attempt"
+
+// Policy describes how many times an operation is retried and how long to wait between attempts.
+type Policy struct {
+	MaxRetries      int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Retryable reports whether a failed attempt may be retried. When nil every error is retried.
+	Retryable func(error) bool
+}
+
+// ExhaustedError is returned by Do when every attempt allowed by the policy has failed.
+type ExhaustedError struct {
+	Attempts int
+	Errors   []error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("retries exhausted after %d attempts: %v", e.Attempts, e.Last())
+}
+
+func (e *ExhaustedError) Unwrap() []error {
+	return e.Errors
+}
+
+// Last returns the error of the final attempt.
+func (e *ExhaustedError) Last() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+// AbortedError is returned by Do when an attempt fails with an error the policy does not retry.
+type AbortedError struct {
+	Attempts int
+	Errors   []error
+}
+
+func (e *AbortedError) Error() string {
+	return fmt.Sprintf("aborted after %d attempts on non-retryable error: %v", e.Attempts, e.Errors[len(e.Errors)-1])
+}
+
+func (e *AbortedError) Unwrap() []error {
+	return e.Errors
+}
+
+// AttemptFromContext returns the 1-based attempt number set by Do, or 0 outside of a retry loop.
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptContextKey).(int); ok {
+		return attempt
+	}
+	return 0
+}
+
+// Backoff returns how long to wait after the given failed attempt before trying again.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	interval := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+	return time.Duration(interval)
+}
+
+// Do calls fn until it succeeds, the policy is exhausted or ctx is cancelled.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	log := logger.GetLoggerFromContext(ctx)
+	errs := make([]error, 0, policy.MaxRetries+1)
+
+	for attempt := 1; ; attempt++ {
+		err := fn(context.WithValue(ctx, attemptContextKey, attempt))
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return &AbortedError{Attempts: attempt, Errors: errs}
+		}
+
+		if attempt > policy.MaxRetries {
+			return &ExhaustedError{Attempts: attempt, Errors: errs}
+		}
+
+		backoff := policy.Backoff(attempt)
+		log.Warn("Attempt failed, retrying",
+			"attempt", attempt,
+			"max_retries", policy.MaxRetries,
+			"backoff", backoff.String(),
+			"error", err.Error(),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errFatal     = errors.New("fatal")
+)
+
+func TestPolicyBackoff(t *testing.T) {
+	policy := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "attempt below one", attempt: 0, want: 100 * time.Millisecond},
+		{name: "first attempt", attempt: 1, want: 100 * time.Millisecond},
+		{name: "third attempt", attempt: 3, want: 400 * time.Millisecond},
+		{name: "capped at max interval", attempt: 10, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       Policy
+		errs         []error
+		wantAttempts int
+		wantErr      error
+		wantExhaust  bool
+		wantAbort    bool
+	}{
+		{name: "succeeds on the first attempt", policy: Policy{MaxRetries: 3}, wantAttempts: 1},
+		{name: "succeeds after retrying", policy: Policy{MaxRetries: 3}, errs: []error{errTransient, errTransient}, wantAttempts: 3},
+		{
+			name:         "exhausted",
+			policy:       Policy{MaxRetries: 2},
+			errs:         []error{errTransient, errTransient, errTransient},
+			wantAttempts: 3,
+			wantErr:      errTransient,
+			wantExhaust:  true,
+		},
+		{
+			name:         "no retries",
+			policy:       Policy{},
+			errs:         []error{errTransient},
+			wantAttempts: 1,
+			wantErr:      errTransient,
+			wantExhaust:  true,
+		},
+		{
+			name: "aborted on a non-retryable error",
+			policy: Policy{MaxRetries: 3, Retryable: func(err error) bool {
+				return !errors.Is(err, errFatal)
+			}},
+			errs:         []error{errTransient, errFatal},
+			wantAttempts: 2,
+			wantErr:      errFatal,
+			wantAbort:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Do(context.Background(), tt.policy, func(ctx context.Context) error {
+				attempts++
+				if got := AttemptFromContext(ctx); got != attempts {
+					t.Errorf("AttemptFromContext() = %d, want %d", got, attempts)
+				}
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("fn called %d times, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Do() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() error = %v, want it to wrap %v", err, tt.wantErr)
+			}
+
+			var exhausted *ExhaustedError
+			if errors.As(err, &exhausted) != tt.wantExhaust {
+				t.Errorf("Do() error = %v, want ExhaustedError %v", err, tt.wantExhaust)
+			}
+			if tt.wantExhaust && (exhausted.Attempts != tt.wantAttempts || exhausted.Last() != tt.wantErr) {
+				t.Errorf("ExhaustedError = %+v, want %d attempts ending with %v", exhausted, tt.wantAttempts, tt.wantErr)
+			}
+
+			var aborted *AbortedError
+			if errors.As(err, &aborted) != tt.wantAbort {
+				t.Errorf("Do() error = %v, want AbortedError %v", err, tt.wantAbort)
+			}
+		})
+	}
+}
+
+func TestDoStopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxRetries: 5, InitialInterval: time.Hour, Multiplier: 1}
+
+	attempts := 0
+	err := Do(ctx, policy, func(context.Context) error {
+		attempts++
+		cancel()
+		return errTransient
+	})
+
+	if attempts != 1 {
+		t.Errorf("fn called %d times, want 1", attempts)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errTransient) {
+		t.Errorf("Do() error = %v, want it to wrap context.Canceled and the last error", err)
+	}
+}
@@ -0,0 +1,227 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+	SchemaTypeJSON     = "JSON"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type Schema struct {
+	ID      int
+	Subject string
+	Version int
+	// Type is empty for Avro, following the registry API.
+	Type   string
+	Schema string
+}
+
+// Client is the subset of the Confluent Schema Registry API used by the payload codecs.
+type Client interface {
+	SchemaByID(ctx context.Context, id int) (*Schema, error)
+	LatestSchema(ctx context.Context, subject string) (*Schema, error)
+	Register(ctx context.Context, subject, schemaType, schema string) (int, error)
+	CheckCompatibility(ctx context.Context, subject, schemaType, schema string) (bool, error)
+}
+
+// TopicSubject returns the subject of a topic's value schemas under the default TopicNameStrategy.
+func TopicSubject(topic string) string {
+	return topic + "-value"
+}
+
+// Error is returned when the registry answers with an error status.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry responded %d (code %d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound reports whether err means the subject, version or schema does not exist.
+func IsNotFound(err error) bool {
+	var registryErr *Error
+	return errors.As(err, &registryErr) && registryErr.StatusCode == http.StatusNotFound
+}
+
+// IsTemporary reports whether the request may succeed if retried: transport failures and server errors.
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	var registryErr *Error
+	if errors.As(err, &registryErr) {
+		return registryErr.StatusCode >= http.StatusInternalServerError || registryErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+type Config struct {
+	URL      string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// HTTPClient talks to a schema registry over its REST API. Schemas fetched by ID are immutable and cached
+// for the life of the client.
+type HTTPClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu   sync.RWMutex
+	byID map[int]*Schema
+}
+
+func NewHTTPClient(cfg Config) *HTTPClient {
+	return &HTTPClient{
+		baseURL:    cfg.URL,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		byID:       make(map[int]*Schema),
+	}
+}
+
+type schemaResponse struct {
+	ID         int    `json:"id"`
+	Subject    string `json:"subject"`
+	Version    int    `json:"version"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+type schemaRequest struct {
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+}
+
+func (c *HTTPClient) SchemaByID(ctx context.Context, id int) (*Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var response schemaResponse
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &response); err != nil {
+		return nil, fmt.Errorf("get schema %d: %w", id, err)
+	}
+
+	schema = &Schema{ID: id, Type: response.SchemaType, Schema: response.Schema}
+
+	c.mu.Lock()
+	c.byID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *HTTPClient) LatestSchema(ctx context.Context, subject string) (*Schema, error) {
+	var response schemaResponse
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &response); err != nil {
+		return nil, fmt.Errorf("get latest schema of subject %s: %w", subject, err)
+	}
+
+	return &Schema{
+		ID:      response.ID,
+		Subject: response.Subject,
+		Version: response.Version,
+		Type:    response.SchemaType,
+		Schema:  response.Schema,
+	}, nil
+}
+
+func (c *HTTPClient) Register(ctx context.Context, subject, schemaType, schema string) (int, error) {
+	var response schemaResponse
+	request := schemaRequest{SchemaType: normalizeSchemaType(schemaType), Schema: schema}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", request, &response); err != nil {
+		return 0, fmt.Errorf("register schema for subject %s: %w", subject, err)
+	}
+	return response.ID, nil
+}
+
+// CheckCompatibility tests schema against the latest version of subject, following the subject's
+// compatibility level. A subject without versions accepts any schema.
+func (c *HTTPClient) CheckCompatibility(ctx context.Context, subject, schemaType, schema string) (bool, error) {
+	var response struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	request := schemaRequest{SchemaType: normalizeSchemaType(schemaType), Schema: schema}
+	err := c.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest", request, &response)
+	if IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check compatibility for subject %s: %w", subject, err)
+	}
+	return response.IsCompatible, nil
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string, body, out any) error {
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, requestBody)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	request.Header.Set("Accept", contentType)
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		registryErr := &Error{StatusCode: response.StatusCode}
+		_ = json.NewDecoder(response.Body).Decode(registryErr)
+		return registryErr
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// normalizeSchemaType maps AVRO to the empty type: it is the registry's default, reported as empty and
+// rejected by some older versions.
+func normalizeSchemaType(schemaType string) string {
+	if schemaType == SchemaTypeAvro {
+		return ""
+	}
+	return schemaType
+}
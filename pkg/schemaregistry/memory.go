@@ -0,0 +1,180 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+)
+
+// MemoryRegistry is an in-process stand-in for a schema registry, used for local runs and tests. It serves
+// the same REST subset as HTTPClient consumes, so it can also be mounted on an httptest server.
+// Compatibility is checked as BACKWARD for Avro subjects; other schema types are always accepted.
+type MemoryRegistry struct {
+	mu       sync.RWMutex
+	schemas  []*Schema
+	subjects map[string][]*Schema
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		subjects: make(map[string][]*Schema),
+	}
+}
+
+func (m *MemoryRegistry) SchemaByID(_ context.Context, id int) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if id < 1 || id > len(m.schemas) {
+		return nil, &Error{StatusCode: http.StatusNotFound, Code: 40403, Message: "Schema " + strconv.Itoa(id) + " not found"}
+	}
+	return m.schemas[id-1], nil
+}
+
+func (m *MemoryRegistry) LatestSchema(_ context.Context, subject string) (*Schema, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := m.subjects[subject]
+	if len(versions) == 0 {
+		return nil, &Error{StatusCode: http.StatusNotFound, Code: 40401, Message: "Subject '" + subject + "' not found."}
+	}
+	return versions[len(versions)-1], nil
+}
+
+// Register returns the ID of an identical schema already registered under subject instead of adding a version.
+func (m *MemoryRegistry) Register(ctx context.Context, subject, schemaType, schema string) (int, error) {
+	compatible, err := m.CheckCompatibility(ctx, subject, schemaType, schema)
+	if err != nil {
+		return 0, err
+	}
+	if !compatible {
+		return 0, &Error{StatusCode: http.StatusConflict, Code: 409, Message: "Schema being registered is incompatible with an earlier schema for subject \"" + subject + "\""}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions := m.subjects[subject]
+	for _, existing := range versions {
+		if existing.Schema == schema && existing.Type == normalizeSchemaType(schemaType) {
+			return existing.ID, nil
+		}
+	}
+
+	registered := &Schema{
+		ID:      len(m.schemas) + 1,
+		Subject: subject,
+		Version: len(versions) + 1,
+		Type:    normalizeSchemaType(schemaType),
+		Schema:  schema,
+	}
+	m.schemas = append(m.schemas, registered)
+	m.subjects[subject] = append(versions, registered)
+	return registered.ID, nil
+}
+
+func (m *MemoryRegistry) CheckCompatibility(ctx context.Context, subject, schemaType, schema string) (bool, error) {
+	latest, err := m.LatestSchema(ctx, subject)
+	if IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if normalizeSchemaType(schemaType) != latest.Type {
+		return false, nil
+	}
+	if latest.Type != "" {
+		return true, nil
+	}
+
+	reader, err := avro.Parse(schema)
+	if err != nil {
+		return false, &Error{StatusCode: http.StatusUnprocessableEntity, Code: 42201, Message: "Invalid schema: " + err.Error()}
+	}
+	writer, err := avro.Parse(latest.Schema)
+	if err != nil {
+		return false, err
+	}
+	return avro.NewSchemaCompatibility().Compatible(reader, writer) == nil, nil
+}
+
+func (m *MemoryRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	segments := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "schemas" && segments[1] == "ids":
+		id, err := strconv.Atoi(segments[2])
+		if err != nil {
+			writeRegistryError(w, &Error{StatusCode: http.StatusNotFound, Code: 40403, Message: "Schema not found"})
+			return
+		}
+		schema, err := m.SchemaByID(r.Context(), id)
+		m.respond(w, err, toSchemaResponse(schema))
+	case r.Method == http.MethodGet && len(segments) == 4 && segments[0] == "subjects" && segments[2] == "versions" && segments[3] == "latest":
+		schema, err := m.LatestSchema(r.Context(), segments[1])
+		m.respond(w, err, toSchemaResponse(schema))
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "subjects" && segments[2] == "versions":
+		var request schemaRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeRegistryError(w, &Error{StatusCode: http.StatusUnprocessableEntity, Code: 42201, Message: err.Error()})
+			return
+		}
+		id, err := m.Register(r.Context(), segments[1], request.SchemaType, request.Schema)
+		m.respond(w, err, schemaResponse{ID: id})
+	case r.Method == http.MethodPost && len(segments) == 5 && segments[0] == "compatibility" && segments[1] == "subjects" && segments[3] == "versions":
+		var request schemaRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeRegistryError(w, &Error{StatusCode: http.StatusUnprocessableEntity, Code: 42201, Message: err.Error()})
+			return
+		}
+		if _, err := m.LatestSchema(r.Context(), segments[2]); err != nil {
+			writeRegistryError(w, err)
+			return
+		}
+		compatible, err := m.CheckCompatibility(r.Context(), segments[2], request.SchemaType, request.Schema)
+		m.respond(w, err, map[string]bool{"is_compatible": compatible})
+	default:
+		writeRegistryError(w, &Error{StatusCode: http.StatusNotFound, Code: 404, Message: "HTTP 404 Not Found"})
+	}
+}
+
+func (m *MemoryRegistry) respond(w http.ResponseWriter, err error, body any) {
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeRegistryError(w http.ResponseWriter, err error) {
+	registryErr, ok := err.(*Error)
+	if !ok {
+		registryErr = &Error{StatusCode: http.StatusInternalServerError, Code: 50001, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(registryErr.StatusCode)
+	_ = json.NewEncoder(w).Encode(registryErr)
+}
+
+func toSchemaResponse(schema *Schema) schemaResponse {
+	if schema == nil {
+		return schemaResponse{}
+	}
+	return schemaResponse{
+		ID:         schema.ID,
+		Subject:    schema.Subject,
+		Version:    schema.Version,
+		SchemaType: schema.Type,
+		Schema:     schema.Schema,
+	}
+}
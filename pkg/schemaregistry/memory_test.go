@@ -0,0 +1,57 @@
+package schemaregistry
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The HTTP client and the memory registry must agree on the REST API they share.
+func TestHTTPClientAgainstMemoryRegistry(t *testing.T) {
+	server := httptest.NewServer(NewMemoryRegistry())
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewHTTPClient(Config{URL: server.URL, Timeout: time.Second})
+
+	if _, err := client.LatestSchema(ctx, "residents-value"); !IsNotFound(err) {
+		t.Fatalf("LatestSchema() of an unknown subject error = %v, want not found", err)
+	}
+	if compatible, err := client.CheckCompatibility(ctx, "residents-value", SchemaTypeAvro, residentSchemaV1); err != nil || !compatible {
+		t.Fatalf("CheckCompatibility() of an unknown subject = %v, %v, want true", compatible, err)
+	}
+
+	id, err := client.Register(ctx, "residents-value", SchemaTypeAvro, residentSchemaV1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	byID, err := client.SchemaByID(ctx, id)
+	if err != nil || byID.Schema != residentSchemaV1 {
+		t.Fatalf("SchemaByID(%d) = %+v, %v", id, byID, err)
+	}
+
+	latest, err := client.LatestSchema(ctx, "residents-value")
+	if err != nil || latest.ID != id || latest.Version != 1 {
+		t.Fatalf("LatestSchema() = %+v, %v, want ID %d version 1", latest, err, id)
+	}
+
+	compatibilityTests := []struct {
+		schema string
+		want   bool
+	}{
+		{schema: residentSchemaV2, want: true},
+		{schema: residentSchemaIncompatible, want: false},
+	}
+	for _, tt := range compatibilityTests {
+		compatible, err := client.CheckCompatibility(ctx, "residents-value", SchemaTypeAvro, tt.schema)
+		if err != nil || compatible != tt.want {
+			t.Errorf("CheckCompatibility(%s) = %v, %v, want %v", tt.schema, compatible, err, tt.want)
+		}
+	}
+
+	if _, err := client.Register(ctx, "residents-value", SchemaTypeAvro, residentSchemaIncompatible); err == nil {
+		t.Error("Register() of an incompatible schema succeeded")
+	}
+}
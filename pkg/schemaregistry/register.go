@@ -0,0 +1,77 @@
+package schemaregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// schemaFileTypes maps schema file extensions to schema types.
+var schemaFileTypes = map[string]string{
+	".avsc":  SchemaTypeAvro,
+	".proto": SchemaTypeProtobuf,
+	".json":  SchemaTypeJSON,
+}
+
+// SchemaDefinition is a schema registered under Subject when the service starts.
+type SchemaDefinition struct {
+	Subject string
+	Type    string
+	Schema  string
+}
+
+// LoadDir reads one schema per file, named after its subject: <subject>.avsc for Avro, <subject>.proto for
+// Protobuf and <subject>.json for JSON Schema. Other files are ignored.
+func LoadDir(dir string) ([]SchemaDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read schemas directory %s: %w", dir, err)
+	}
+
+	var schemas []SchemaDefinition
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		schemaType, ok := schemaFileTypes[extension]
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read schema file %s: %w", entry.Name(), err)
+		}
+		schemas = append(schemas, SchemaDefinition{
+			Subject: strings.TrimSuffix(entry.Name(), extension),
+			Type:    schemaType,
+			Schema:  string(content),
+		})
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Subject < schemas[j].Subject })
+	return schemas, nil
+}
+
+// RegisterAll checks every schema against the latest version of its subject before registering any, so an
+// incompatible schema fails at startup rather than on the first publish. Registering a schema that is
+// already the latest version is a no-op.
+func RegisterAll(ctx context.Context, client Client, schemas []SchemaDefinition) error {
+	for _, schema := range schemas {
+		compatible, err := client.CheckCompatibility(ctx, schema.Subject, schema.Type, schema.Schema)
+		if err != nil {
+			return err
+		}
+		if !compatible {
+			return fmt.Errorf("schema for subject %s is incompatible with its latest version", schema.Subject)
+		}
+	}
+
+	for _, schema := range schemas {
+		if _, err := client.Register(ctx, schema.Subject, schema.Type, schema.Schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package schemaregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	residentSchemaV1 = `{"type":"record","name":"Resident","fields":[{"name":"name","type":"string"}]}`
+	// Adding a field with a default keeps the subject backward compatible.
+	residentSchemaV2 = `{"type":"record","name":"Resident","fields":[{"name":"name","type":"string"},{"name":"phone","type":"string","default":""}]}`
+	// Adding a field without a default does not.
+	residentSchemaIncompatible = `{"type":"record","name":"Resident","fields":[{"name":"name","type":"string"},{"name":"apartment","type":"string"}]}`
+)
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"residents-value.avsc":  residentSchemaV1,
+		"commands-value.proto":  `syntax = "proto3"; message Command {}`,
+		"README.md":             "ignored",
+		"deliveries-value.json": `{"type":"object"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	schemas, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+
+	want := []SchemaDefinition{
+		{Subject: "commands-value", Type: SchemaTypeProtobuf, Schema: files["commands-value.proto"]},
+		{Subject: "deliveries-value", Type: SchemaTypeJSON, Schema: files["deliveries-value.json"]},
+		{Subject: "residents-value", Type: SchemaTypeAvro, Schema: files["residents-value.avsc"]},
+	}
+	if len(schemas) != len(want) {
+		t.Fatalf("got %d schemas, want %d", len(schemas), len(want))
+	}
+	for i := range want {
+		if schemas[i] != want[i] {
+			t.Errorf("schema %d = %+v, want %+v", i, schemas[i], want[i])
+		}
+	}
+}
+
+func TestRegisterAll(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    []string
+		schema      string
+		wantErr     bool
+		wantVersion int
+	}{
+		{name: "new subject", schema: residentSchemaV1, wantVersion: 1},
+		{name: "same schema again", existing: []string{residentSchemaV1}, schema: residentSchemaV1, wantVersion: 1},
+		{name: "compatible evolution", existing: []string{residentSchemaV1}, schema: residentSchemaV2, wantVersion: 2},
+		{name: "incompatible evolution", existing: []string{residentSchemaV1}, schema: residentSchemaIncompatible, wantErr: true, wantVersion: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			registry := NewMemoryRegistry()
+			for _, schema := range tt.existing {
+				if _, err := registry.Register(ctx, "residents-value", SchemaTypeAvro, schema); err != nil {
+					t.Fatalf("register existing schema: %v", err)
+				}
+			}
+
+			err := RegisterAll(ctx, registry, []SchemaDefinition{{Subject: "residents-value", Type: SchemaTypeAvro, Schema: tt.schema}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegisterAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			latest, err := registry.LatestSchema(ctx, "residents-value")
+			if err != nil {
+				t.Fatalf("LatestSchema() error = %v", err)
+			}
+			if latest.Version != tt.wantVersion {
+				t.Errorf("latest version = %d, want %d", latest.Version, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package watermill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// PingBrokers passes when at least one broker accepts a connection within the deadline of ctx.
+func PingBrokers(ctx context.Context, brokers []string) error {
+	saramaConfig := sarama.NewConfig()
+	if deadline, ok := ctx.Deadline(); ok {
+		saramaConfig.Net.DialTimeout = time.Until(deadline)
+	}
+
+	var errs []error
+	for _, addr := range brokers {
+		broker := sarama.NewBroker(addr)
+		if err := broker.Open(saramaConfig); err != nil {
+			errs = append(errs, fmt.Errorf("broker %s: %w", addr, err))
+			continue
+		}
+
+		connected, err := broker.Connected()
+		_ = broker.Close()
+		if connected {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("broker %s: %w", addr, err))
+	}
+
+	return fmt.Errorf("no kafka broker reachable: %w", errors.Join(errs...))
+}
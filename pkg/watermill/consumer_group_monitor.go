@@ -0,0 +1,359 @@
+package watermill
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+)
+
+// PartitionStatus describes a partition claimed by the consumer group member.
+type PartitionStatus struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	// AssignedOffset is the offset consumption resumed from when the partition was claimed.
+	AssignedOffset int64 `json:"assigned_offset"`
+	// CommittedOffset is the next offset to consume, as marked after the last processed message. Sarama
+	// commits it within Consumer.Offsets.AutoCommit.Interval. It is negative while the group has no offset
+	// for the partition, until the first message is marked.
+	CommittedOffset int64 `json:"committed_offset"`
+	HighWaterMark   int64 `json:"high_water_mark"`
+	// Lag is only meaningful when OffsetKnown is true.
+	Lag          int64     `json:"lag"`
+	OffsetKnown  bool      `json:"offset_known"`
+	LastProgress time.Time `json:"last_progress"`
+}
+
+// GroupStatus is a snapshot of the member's consumer group session.
+type GroupStatus struct {
+	ConsumerGroup string            `json:"consumer_group"`
+	Assigned      bool              `json:"assigned"`
+	MemberID      string            `json:"member_id,omitempty"`
+	GenerationID  int32             `json:"generation_id,omitempty"`
+	JoinedAt      time.Time         `json:"joined_at,omitzero"`
+	Partitions    []PartitionStatus `json:"partitions"`
+}
+
+type partitionState struct {
+	claim           sarama.ConsumerGroupClaim
+	assignedOffset  int64
+	committedOffset int64
+	lastProgress    time.Time
+}
+
+type partitionID struct {
+	topic     string
+	partition int32
+}
+
+// ConsumerGroupMonitor is a kafka.SaramaTracer that watches the consumer group session instead of tracing
+// it: it records rebalances, the claimed partitions and the offsets marked after each processed message.
+// It also pauses fetching, since the Watermill subscriber does not expose the Sarama consumer group.
+type ConsumerGroupMonitor struct {
+	consumerGroup string
+	logger        appLogger.Logger
+
+	mu           sync.RWMutex
+	inSession    bool
+	memberID     string
+	generationID int32
+	joinedAt     time.Time
+	partitions   map[partitionID]*partitionState
+	paused       bool
+	// running is closed while fetching is not paused.
+	running chan struct{}
+}
+
+// pausable is implemented by the claims of a Sarama consumer group, which embed their partition consumer.
+type pausable interface {
+	Pause()
+	Resume()
+}
+
+var _ kafka.SaramaTracer = (*ConsumerGroupMonitor)(nil)
+
+func NewConsumerGroupMonitor(consumerGroup string, logger appLogger.Logger) *ConsumerGroupMonitor {
+	running := make(chan struct{})
+	close(running)
+
+	return &ConsumerGroupMonitor{
+		consumerGroup: consumerGroup,
+		logger:        logger,
+		partitions:    make(map[partitionID]*partitionState),
+		running:       running,
+	}
+}
+
+// SetPaused stops or restarts fetching from every claimed partition. While paused, no message is handed to
+// the Watermill subscriber, yet a rebalance still ends the session promptly: nothing is left blocked waiting
+// for a consumer that is not reading, so the member is not evicted from the group.
+func (m *ConsumerGroupMonitor) SetPaused(paused bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paused == paused {
+		return
+	}
+	m.paused = paused
+
+	if paused {
+		m.running = make(chan struct{})
+	} else {
+		close(m.running)
+	}
+	for _, state := range m.partitions {
+		pausePartition(state.claim, paused)
+	}
+}
+
+func (m *ConsumerGroupMonitor) runningSignal() <-chan struct{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running
+}
+
+func pausePartition(claim sarama.ConsumerGroupClaim, paused bool) {
+	partition, ok := claim.(pausable)
+	if !ok {
+		return
+	}
+	if paused {
+		partition.Pause()
+	} else {
+		partition.Resume()
+	}
+}
+
+// Assigned reports whether the member currently takes part in a consumer group session. A member can be
+// in a session without partitions when the group has more members than the topic has partitions.
+func (m *ConsumerGroupMonitor) Assigned() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inSession
+}
+
+// Status returns the session and the claimed partitions ordered by topic and partition.
+func (m *ConsumerGroupMonitor) Status() GroupStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := GroupStatus{
+		ConsumerGroup: m.consumerGroup,
+		Assigned:      m.inSession,
+		MemberID:      m.memberID,
+		GenerationID:  m.generationID,
+		JoinedAt:      m.joinedAt,
+		Partitions:    make([]PartitionStatus, 0, len(m.partitions)),
+	}
+
+	for id, state := range m.partitions {
+		partition := PartitionStatus{
+			Topic:           id.topic,
+			Partition:       id.partition,
+			AssignedOffset:  state.assignedOffset,
+			CommittedOffset: state.committedOffset,
+			LastProgress:    state.lastProgress,
+		}
+		if state.claim != nil {
+			partition.HighWaterMark = state.claim.HighWaterMarkOffset()
+		}
+		// Sarama reports OffsetOldest or OffsetNewest, both negative, for a partition without a committed
+		// offset, so the lag is unknown until the first message is marked.
+		if state.claim != nil && state.committedOffset >= 0 {
+			partition.OffsetKnown = true
+			partition.Lag = max(partition.HighWaterMark-state.committedOffset, 0)
+		}
+		status.Partitions = append(status.Partitions, partition)
+	}
+
+	sort.Slice(status.Partitions, func(i, j int) bool {
+		if status.Partitions[i].Topic != status.Partitions[j].Topic {
+			return status.Partitions[i].Topic < status.Partitions[j].Topic
+		}
+		return status.Partitions[i].Partition < status.Partitions[j].Partition
+	})
+	return status
+}
+
+// StalledPartitions returns the partitions that still have lag but made no progress within timeout.
+// Partitions whose offset is unknown are never reported.
+func (m *ConsumerGroupMonitor) StalledPartitions(timeout time.Duration) []PartitionStatus {
+	var stalled []PartitionStatus
+	for _, partition := range m.Status().Partitions {
+		if partition.OffsetKnown && partition.Lag > 0 && time.Since(partition.LastProgress) > timeout {
+			stalled = append(stalled, partition)
+		}
+	}
+	return stalled
+}
+
+func (m *ConsumerGroupMonitor) setup(session sarama.ConsumerGroupSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inSession = true
+	m.memberID = session.MemberID()
+	m.generationID = session.GenerationID()
+	m.joinedAt = time.Now()
+	m.partitions = make(map[partitionID]*partitionState)
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			m.partitions[partitionID{topic: topic, partition: partition}] = &partitionState{lastProgress: m.joinedAt}
+		}
+	}
+
+	for topic := range session.Claims() {
+		metrics.ConsumerRebalances.WithLabelValues(topic, m.consumerGroup).Inc()
+	}
+
+	m.logger.Info("Consumer group session started",
+		"member_id", m.memberID,
+		"generation_id", m.generationID,
+		"claims", session.Claims(),
+	)
+}
+
+func (m *ConsumerGroupMonitor) cleanup(session sarama.ConsumerGroupSession) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inSession = false
+	m.partitions = make(map[partitionID]*partitionState)
+
+	m.logger.Info("Consumer group session ended, partitions revoked",
+		"member_id", session.MemberID(),
+		"generation_id", session.GenerationID(),
+		"claims", session.Claims(),
+	)
+}
+
+func (m *ConsumerGroupMonitor) claim(claim sarama.ConsumerGroupClaim) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := partitionID{topic: claim.Topic(), partition: claim.Partition()}
+	state, ok := m.partitions[id]
+	if !ok {
+		state = &partitionState{lastProgress: time.Now()}
+		m.partitions[id] = state
+	}
+	state.claim = claim
+	state.assignedOffset = claim.InitialOffset()
+	state.committedOffset = claim.InitialOffset()
+	if m.paused {
+		pausePartition(claim, true)
+	}
+}
+
+// gate forwards the messages of claim while fetching is not paused, and closes the returned claim's channel
+// as soon as the session ends, so the Watermill handler returns even while paused.
+func (m *ConsumerGroupMonitor) gate(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) sarama.ConsumerGroupClaim {
+	messages := make(chan *sarama.ConsumerMessage)
+	done := session.Context().Done()
+
+	go func() {
+		defer close(messages)
+		for {
+			select {
+			case <-m.runningSignal():
+			case <-done:
+				return
+			}
+
+			select {
+			case msg, ok := <-claim.Messages():
+				if !ok {
+					return
+				}
+				select {
+				case messages <- msg:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return &gatedClaim{ConsumerGroupClaim: claim, messages: messages}
+}
+
+type gatedClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *gatedClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func (m *ConsumerGroupMonitor) markOffset(topic string, partition int32, nextOffset int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.partitions[partitionID{topic: topic, partition: partition}]
+	if !ok {
+		return
+	}
+	state.committedOffset = nextOffset
+	state.lastProgress = time.Now()
+}
+
+func (m *ConsumerGroupMonitor) WrapConsumerGroupHandler(handler sarama.ConsumerGroupHandler) sarama.ConsumerGroupHandler {
+	return &monitoredGroupHandler{ConsumerGroupHandler: handler, monitor: m}
+}
+
+func (m *ConsumerGroupMonitor) WrapConsumer(consumer sarama.Consumer) sarama.Consumer {
+	return consumer
+}
+
+func (m *ConsumerGroupMonitor) WrapPartitionConsumer(consumer sarama.PartitionConsumer) sarama.PartitionConsumer {
+	return consumer
+}
+
+func (m *ConsumerGroupMonitor) WrapSyncProducer(_ *sarama.Config, producer sarama.SyncProducer) sarama.SyncProducer {
+	return producer
+}
+
+type monitoredGroupHandler struct {
+	sarama.ConsumerGroupHandler
+	monitor *ConsumerGroupMonitor
+}
+
+func (h *monitoredGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.monitor.setup(session)
+	return h.ConsumerGroupHandler.Setup(session)
+}
+
+func (h *monitoredGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	h.monitor.cleanup(session)
+	return h.ConsumerGroupHandler.Cleanup(session)
+}
+
+func (h *monitoredGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	h.monitor.claim(claim)
+	return h.ConsumerGroupHandler.ConsumeClaim(
+		&monitoredSession{ConsumerGroupSession: session, monitor: h.monitor},
+		h.monitor.gate(session, claim),
+	)
+}
+
+type monitoredSession struct {
+	sarama.ConsumerGroupSession
+	monitor *ConsumerGroupMonitor
+}
+
+func (s *monitoredSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.ConsumerGroupSession.MarkMessage(msg, metadata)
+	s.monitor.markOffset(msg.Topic, msg.Partition, msg.Offset+1)
+}
+
+func (s *monitoredSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.ConsumerGroupSession.MarkOffset(topic, partition, offset, metadata)
+	s.monitor.markOffset(topic, partition, offset)
+}
@@ -0,0 +1,129 @@
+package watermill
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+)
+
+type stubClaim struct {
+	initialOffset int64
+	highWaterMark int64
+}
+
+func (c stubClaim) Topic() string                            { return "topic" }
+func (c stubClaim) Partition() int32                         { return 0 }
+func (c stubClaim) InitialOffset() int64                     { return c.initialOffset }
+func (c stubClaim) HighWaterMarkOffset() int64               { return c.highWaterMark }
+func (c stubClaim) Messages() <-chan *sarama.ConsumerMessage { return nil }
+
+func TestConsumerGroupMonitorLag(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialOffset int64
+		markedOffset  int64
+		wantKnown     bool
+		wantLag       int64
+		wantStalled   bool
+	}{
+		{name: "committed offset behind the high-water mark", initialOffset: 4, wantKnown: true, wantLag: 6, wantStalled: true},
+		{name: "caught up", initialOffset: 10, wantKnown: true, wantStalled: false},
+		{name: "no committed offset yet", initialOffset: sarama.OffsetOldest, wantStalled: false},
+		{name: "no committed offset with newest initial offset", initialOffset: sarama.OffsetNewest, wantStalled: false},
+		{name: "first message marked", initialOffset: sarama.OffsetOldest, markedOffset: 3, wantKnown: true, wantLag: 7, wantStalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := NewConsumerGroupMonitor("group", appLogger.NewNoopLogger())
+			monitor.claim(stubClaim{initialOffset: tt.initialOffset, highWaterMark: 10})
+			if tt.markedOffset > 0 {
+				monitor.markOffset("topic", 0, tt.markedOffset)
+			}
+
+			partitions := monitor.Status().Partitions
+			if len(partitions) != 1 {
+				t.Fatalf("got %d partitions, want 1", len(partitions))
+			}
+			if partitions[0].OffsetKnown != tt.wantKnown {
+				t.Errorf("OffsetKnown = %v, want %v", partitions[0].OffsetKnown, tt.wantKnown)
+			}
+			if partitions[0].Lag != tt.wantLag {
+				t.Errorf("Lag = %d, want %d", partitions[0].Lag, tt.wantLag)
+			}
+
+			// A negative timeout treats every partition as idle.
+			stalled := len(monitor.StalledPartitions(-time.Second)) > 0
+			if stalled != tt.wantStalled {
+				t.Errorf("stalled = %v, want %v", stalled, tt.wantStalled)
+			}
+		})
+	}
+}
+
+type stubSession struct {
+	sarama.ConsumerGroupSession
+	ctx context.Context
+}
+
+func (s stubSession) Context() context.Context { return s.ctx }
+
+type pausableClaim struct {
+	stubClaim
+	messages chan *sarama.ConsumerMessage
+	paused   atomic.Bool
+}
+
+func (c *pausableClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+func (c *pausableClaim) Pause()                                   { c.paused.Store(true) }
+func (c *pausableClaim) Resume()                                  { c.paused.Store(false) }
+
+func TestConsumerGroupMonitorPause(t *testing.T) {
+	monitor := NewConsumerGroupMonitor("group", appLogger.NewNoopLogger())
+	sessionCtx, endSession := context.WithCancel(context.Background())
+	defer endSession()
+
+	claim := &pausableClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	monitor.claim(claim)
+	gated := monitor.gate(stubSession{ctx: sessionCtx}, claim)
+
+	monitor.SetPaused(true)
+	if !claim.paused.Load() {
+		t.Fatal("partition not paused")
+	}
+	claim.messages <- &sarama.ConsumerMessage{Offset: 1}
+	select {
+	case <-gated.Messages():
+		t.Fatal("message forwarded while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	monitor.SetPaused(false)
+	if claim.paused.Load() {
+		t.Fatal("partition not resumed")
+	}
+	select {
+	case msg := <-gated.Messages():
+		if msg.Offset != 1 {
+			t.Fatalf("got offset %d, want 1", msg.Offset)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not forwarded after resume")
+	}
+
+	// A rebalance while paused must not wait for the consumer.
+	monitor.SetPaused(true)
+	endSession()
+	select {
+	case _, ok := <-gated.Messages():
+		if ok {
+			t.Fatal("message forwarded after the session ended")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("gated claim not closed after the session ended")
+	}
+}
@@ -0,0 +1,57 @@
+package watermill
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+func setDeadLetterMetadata(msg *message.Message, deadLetter *pubsub.DeadLetter) error {
+	errorStack, err := json.Marshal(deadLetter.Errors)
+	if err != nil {
+		return err
+	}
+
+	msg.Metadata.Set(pubsub.OriginalPartitionHeader, strconv.FormatInt(int64(deadLetter.OriginalPartition), 10))
+	msg.Metadata.Set(pubsub.OriginalOffsetHeader, strconv.FormatInt(deadLetter.OriginalOffset, 10))
+	msg.Metadata.Set(pubsub.ConsumerGroupHeader, deadLetter.ConsumerGroup)
+	msg.Metadata.Set(pubsub.FailureReasonHeader, deadLetter.FailureReason)
+	msg.Metadata.Set(pubsub.AttemptCountHeader, strconv.Itoa(deadLetter.AttemptCount))
+	msg.Metadata.Set(pubsub.ErrorStackHeader, string(errorStack))
+	msg.Metadata.Set(pubsub.FailedAtHeader, deadLetter.FailedAt.UTC().Format(time.RFC3339Nano))
+
+	return nil
+}
+
+// extractDeadLetterMetadata returns nil when the message carries no dead letter headers.
+// Malformed values are kept at their zero value so a damaged DLQ entry can still be inspected.
+func extractDeadLetterMetadata(msg *message.Message) *pubsub.DeadLetter {
+	failureReason := msg.Metadata.Get(pubsub.FailureReasonHeader)
+	if failureReason == "" {
+		return nil
+	}
+
+	deadLetter := &pubsub.DeadLetter{
+		ConsumerGroup: msg.Metadata.Get(pubsub.ConsumerGroupHeader),
+		FailureReason: failureReason,
+	}
+
+	if partition, err := strconv.ParseInt(msg.Metadata.Get(pubsub.OriginalPartitionHeader), 10, 32); err == nil {
+		deadLetter.OriginalPartition = int32(partition)
+	}
+	if offset, err := strconv.ParseInt(msg.Metadata.Get(pubsub.OriginalOffsetHeader), 10, 64); err == nil {
+		deadLetter.OriginalOffset = offset
+	}
+	if attempts, err := strconv.Atoi(msg.Metadata.Get(pubsub.AttemptCountHeader)); err == nil {
+		deadLetter.AttemptCount = attempts
+	}
+	if failedAt, err := time.Parse(time.RFC3339Nano, msg.Metadata.Get(pubsub.FailedAtHeader)); err == nil {
+		deadLetter.FailedAt = failedAt
+	}
+	_ = json.Unmarshal([]byte(msg.Metadata.Get(pubsub.ErrorStackHeader)), &deadLetter.Errors)
+
+	return deadLetter
+}
@@ -1,7 +1,50 @@
 package watermill
 
-import "github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+import (
+	"github.com/IBM/sarama"
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
 
-func NewWatermillMarshaler() kafka.Marshaler {
-	return kafka.DefaultMarshaler{}
+// keyMarshaler uses the Key header as the Kafka message key so every message of the same aggregate is
+// written to the same partition and consumed in order.
+type keyMarshaler struct {
+	kafka.DefaultMarshaler
+}
+
+func NewWatermillMarshaler() kafka.MarshalerUnmarshaler {
+	return keyMarshaler{}
+}
+
+func (m keyMarshaler) Marshal(topic string, msg *message.Message) (*sarama.ProducerMessage, error) {
+	kafkaMessage, err := m.DefaultMarshaler.Marshal(topic, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	kafkaMessage.Key = sarama.StringEncoder(partitionKey(msg))
+	return kafkaMessage, nil
+}
+
+// Unmarshal restores the Key header from the Kafka message key for producers that do not send it.
+func (m keyMarshaler) Unmarshal(kafkaMessage *sarama.ConsumerMessage) (*message.Message, error) {
+	msg, err := m.DefaultMarshaler.Unmarshal(kafkaMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Metadata.Get(pubsub.KeyHeader) == "" && len(kafkaMessage.Key) > 0 {
+		msg.Metadata.Set(pubsub.KeyHeader, string(kafkaMessage.Key))
+	}
+	return msg, nil
+}
+
+// partitionKey falls back to the message UUID when there is no Key header, which spreads unkeyed messages
+// across partitions while keeping a redelivered message on the partition it was first written to.
+func partitionKey(msg *message.Message) string {
+	if key := msg.Metadata.Get(pubsub.KeyHeader); key != "" {
+		return key
+	}
+	return msg.UUID
 }
@@ -0,0 +1,20 @@
+package watermill
+
+import (
+	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+const memoryOutputChannelBuffer = 256
+
+// NewMemoryPubSub returns an in-process Pub/Sub used as both publisher and subscriber when running without Kafka.
+// Messages are not kept: those published to a topic before anyone subscribes to it are dropped. It is meant
+// for tests and local runs only.
+func NewMemoryPubSub(logger appLogger.Logger) *gochannel.GoChannel {
+	return gochannel.NewGoChannel(
+		gochannel.Config{
+			OutputChannelBuffer: memoryOutputChannelBuffer,
+		},
+		NewWatermillLoggerFromLogger(logger),
+	)
+}
@@ -1,24 +1,50 @@
 package watermill
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
 	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/google/uuid"
 )
 
-func ConvertPubsubToWatermill[T any](pubsubMessage *pubsub.Message[T], logger appLogger.Logger) (*message.Message, error) {
-	payloadBytes, err := json.Marshal(pubsubMessage.Payload)
+func ConvertPubsubToWatermill[T any](
+	ctx context.Context,
+	topic string,
+	pubsubMessage *pubsub.Message[T],
+	codecs *codec.Registry,
+	logger appLogger.Logger,
+) (*message.Message, error) {
+	payloadBytes, err := encodePayload(ctx, topic, pubsubMessage, codecs)
 	if err != nil {
 		return nil, err
 	}
 
-	msg := message.NewMessage(uuid.New().String(), payloadBytes)
+	messageID := pubsubMessage.Headers.MessageID
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	msg := message.NewMessage(messageID, payloadBytes)
+	msg.Metadata.Set(pubsub.MessageIDHeader, messageID)
+	if pubsubMessage.Headers.CorrelationID != "" {
+		msg.Metadata.Set(pubsub.CorrelationIDHeader, pubsubMessage.Headers.CorrelationID)
+	}
+	if pubsubMessage.Headers.CausationID != "" {
+		msg.Metadata.Set(pubsub.CausationIDHeader, pubsubMessage.Headers.CausationID)
+	}
 	msg.Metadata.Set(pubsub.EventTypeHeader, pubsubMessage.Headers.EventType)
+	if pubsubMessage.Headers.EventVersion > 0 {
+		msg.Metadata.Set(pubsub.EventVersionHeader, strconv.Itoa(pubsubMessage.Headers.EventVersion))
+	}
+	msg.Metadata.Set(pubsub.ContentTypeHeader, codec.Normalize(pubsubMessage.Headers.ContentType))
 	if pubsubMessage.Headers.Key != "" {
 		msg.Metadata.Set(pubsub.KeyHeader, pubsubMessage.Headers.Key)
 	}
@@ -26,26 +52,52 @@ func ConvertPubsubToWatermill[T any](pubsubMessage *pubsub.Message[T], logger ap
 	if pubsubMessage.Headers.OriginalTopic != nil {
 		msg.Metadata.Set(pubsub.OriginalTopicHeader, *pubsubMessage.Headers.OriginalTopic)
 	}
+	if pubsubMessage.Headers.ReplayCount > 0 {
+		msg.Metadata.Set(pubsub.ReplayCountHeader, strconv.Itoa(pubsubMessage.Headers.ReplayCount))
+	}
+	for field, value := range pubsubMessage.Headers.TraceContext {
+		msg.Metadata.Set(field, value)
+	}
+	if pubsubMessage.Headers.DeadLetter != nil {
+		if err := setDeadLetterMetadata(msg, pubsubMessage.Headers.DeadLetter); err != nil {
+			return nil, err
+		}
+	}
 
 	return msg, nil
 }
 
+// ConvertWatermillToPubsub unwraps the data of JSON payloads. Payloads of other content types are kept as
+// []byte, since they can only be decoded once the handler's payload type is known.
 func ConvertWatermillToPubsub(msg *message.Message, err *error) (*pubsub.Message[any], error) {
-	var rawData any
-	if unmarshalErr := json.Unmarshal(msg.Payload, &rawData); unmarshalErr != nil {
-		return nil, unmarshalErr
+	contentType := msg.Metadata.Get(pubsub.ContentTypeHeader)
+
+	var data any = []byte(msg.Payload)
+	if codec.IsJSON(contentType) {
+		var rawData any
+		if unmarshalErr := json.Unmarshal(msg.Payload, &rawData); unmarshalErr != nil {
+			return nil, unmarshalErr
+		}
+		data = extractDataFromPayload(rawData)
 	}
 
-	data := extractDataFromPayload(rawData)
 	headers := pubsub.Headers{
-		EventType: msg.Metadata.Get(pubsub.EventTypeHeader),
-		Key:       msg.Metadata.Get(pubsub.KeyHeader),
-		Source:    msg.Metadata.Get(pubsub.SourceHeader),
+		MessageID:     extractMessageID(msg),
+		CorrelationID: msg.Metadata.Get(pubsub.CorrelationIDHeader),
+		CausationID:   msg.Metadata.Get(pubsub.CausationIDHeader),
+		EventType:     msg.Metadata.Get(pubsub.EventTypeHeader),
+		EventVersion:  extractEventVersion(msg),
+		Key:           msg.Metadata.Get(pubsub.KeyHeader),
+		Source:        msg.Metadata.Get(pubsub.SourceHeader),
+		ContentType:   contentType,
 	}
 
 	if originalTopic := msg.Metadata.Get(pubsub.OriginalTopicHeader); originalTopic != "" {
 		headers.OriginalTopic = &originalTopic
 	}
+	headers.ReplayCount = extractReplayCount(msg)
+	headers.DeadLetter = extractDeadLetterMetadata(msg)
+	headers.TraceContext = extractTraceContext(msg)
 
 	convertedMessage := pubsub.NewMessage(msg.Context(), headers, data)
 	return convertedMessage, nil
@@ -53,14 +105,23 @@ func ConvertWatermillToPubsub(msg *message.Message, err *error) (*pubsub.Message
 
 func BuildRawDLQMessage(msg *message.Message, handlerErr, convertErr error) *pubsub.Message[any] {
 	headers := pubsub.Headers{
-		EventType: msg.Metadata.Get(pubsub.EventTypeHeader),
-		Key:       msg.Metadata.Get(pubsub.KeyHeader),
-		Source:    msg.Metadata.Get(pubsub.SourceHeader),
+		MessageID:     extractMessageID(msg),
+		CorrelationID: msg.Metadata.Get(pubsub.CorrelationIDHeader),
+		CausationID:   msg.Metadata.Get(pubsub.CausationIDHeader),
+		EventType:     msg.Metadata.Get(pubsub.EventTypeHeader),
+		EventVersion:  extractEventVersion(msg),
+		Key:           msg.Metadata.Get(pubsub.KeyHeader),
+		Source:        msg.Metadata.Get(pubsub.SourceHeader),
+		// Only JSON payloads fail to convert, so the raw payload is sent with the content type it came with.
+		ContentType: msg.Metadata.Get(pubsub.ContentTypeHeader),
 	}
 
 	if originalTopic := msg.Metadata.Get(pubsub.OriginalTopicHeader); originalTopic != "" {
 		headers.OriginalTopic = &originalTopic
 	}
+	headers.ReplayCount = extractReplayCount(msg)
+	headers.DeadLetter = extractDeadLetterMetadata(msg)
+	headers.TraceContext = extractTraceContext(msg)
 
 	rawData := map[string]any{
 		"raw_payload_base64": base64.StdEncoding.EncodeToString(msg.Payload),
@@ -72,6 +133,33 @@ func BuildRawDLQMessage(msg *message.Message, handlerErr, convertErr error) *pub
 	return pubsub.NewMessage[any](msg.Context(), headers, rawData)
 }
 
+// encodePayload serializes the data with the codec of the message content type, wrapping JSON in the
+// {"data": ...} envelope. Data of other content types that is already []byte, as when a consumed message is
+// dead-lettered or replayed, is sent as is.
+func encodePayload[T any](ctx context.Context, topic string, pubsubMessage *pubsub.Message[T], codecs *codec.Registry) ([]byte, error) {
+	contentType := pubsubMessage.Headers.ContentType
+	data := any(pubsubMessage.Payload.Data)
+
+	if encoded, ok := data.([]byte); ok && !codec.IsJSON(contentType) {
+		return encoded, nil
+	}
+
+	payloadCodec, err := codecs.Get(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := payloadCodec.Encode(ctx, topic, data)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", payloadCodec.ContentType(), err)
+	}
+
+	if codec.IsJSON(contentType) {
+		return json.Marshal(pubsub.Payload[json.RawMessage]{Data: encoded})
+	}
+	return encoded, nil
+}
+
 // IsValidJSONPayload reports whether the message payload can be parsed as JSON.
 func IsValidJSONPayload(payload []byte) bool {
 	if len(payload) == 0 {
@@ -80,6 +168,45 @@ func IsValidJSONPayload(payload []byte) bool {
 	return json.Valid(payload)
 }
 
+// extractMessageID prefers the MessageID header, which survives brokers that do not carry Watermill's UUID.
+func extractMessageID(msg *message.Message) string {
+	if messageID := msg.Metadata.Get(pubsub.MessageIDHeader); messageID != "" {
+		return messageID
+	}
+	return msg.UUID
+}
+
+func extractTraceContext(msg *message.Message) map[string]string {
+	var traceContext map[string]string
+	for _, field := range tracing.Fields() {
+		value := msg.Metadata.Get(field)
+		if value == "" {
+			continue
+		}
+		if traceContext == nil {
+			traceContext = make(map[string]string)
+		}
+		traceContext[field] = value
+	}
+	return traceContext
+}
+
+func extractEventVersion(msg *message.Message) int {
+	version, err := strconv.Atoi(msg.Metadata.Get(pubsub.EventVersionHeader))
+	if err != nil || version < 0 {
+		return 0
+	}
+	return version
+}
+
+func extractReplayCount(msg *message.Message) int {
+	replayCount, err := strconv.Atoi(msg.Metadata.Get(pubsub.ReplayCountHeader))
+	if err != nil {
+		return 0
+	}
+	return replayCount
+}
+
 func extractDataFromPayload(rawData any) any {
 	payloadMap, ok := rawData.(map[string]any)
 	if !ok {
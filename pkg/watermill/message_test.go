@@ -0,0 +1,49 @@
+package watermill
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+func TestConvertWatermillToPubsubKeepsTraceContext(t *testing.T) {
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	msg := message.NewMessage("message-1", []byte(`{"data":{}}`))
+	msg.Metadata.Set("traceparent", traceparent)
+	msg.Metadata.Set("tracestate", "vendor=value")
+
+	converted, err := ConvertWatermillToPubsub(msg, nil)
+	if err != nil {
+		t.Fatalf("ConvertWatermillToPubsub() error = %v", err)
+	}
+
+	traceContext := converted.Headers.TraceContext
+	if got := traceContext["traceparent"]; got != traceparent {
+		t.Errorf("traceparent = %q, want %q", got, traceparent)
+	}
+	if got := traceContext["tracestate"]; got != "vendor=value" {
+		t.Errorf("tracestate = %q, want %q", got, "vendor=value")
+	}
+}
+
+func TestBuildRawDLQMessageKeepsMessageIDAndContentType(t *testing.T) {
+	msg := message.NewMessage("message-1", []byte(`{not json`))
+	msg.Metadata.Set(pubsub.MessageIDHeader, "message-1")
+	msg.Metadata.Set(pubsub.ContentTypeHeader, "application/json")
+	msg.Metadata.Set(pubsub.KeyHeader, "resident-1")
+
+	headers := BuildRawDLQMessage(msg, errors.New("handler failed"), errors.New("invalid JSON")).Headers
+	if headers.MessageID != "message-1" {
+		t.Errorf("MessageID = %q, want %q", headers.MessageID, "message-1")
+	}
+	if headers.ContentType != "application/json" {
+		t.Errorf("ContentType = %q, want %q", headers.ContentType, "application/json")
+	}
+	if headers.Key != "resident-1" {
+		t.Errorf("Key = %q, want %q", headers.Key, "resident-1")
+	}
+}
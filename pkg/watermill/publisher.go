@@ -2,18 +2,40 @@ package watermill
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/codec"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/events"
 	appLogger "github.com/Moreira-Henrique-Pedro/entregador/pkg/logger"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/metrics"
+	"github.com/Moreira-Henrique-Pedro/entregador/pkg/tracing"
 	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
 	"github.com/ThreeDotsLabs/watermill/message"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// publishErrorWindow is how long a failed publish keeps the publisher unhealthy when nothing is published
+// after it, so a single failure does not keep the service unready once the broker has recovered.
+const publishErrorWindow = time.Minute
+
 type WatermillPublisher[T any] struct {
-	publisher message.Publisher
+	publisher   message.Publisher
+	codecs      *codec.Registry
+	errorWindow time.Duration
+
+	mu              sync.RWMutex
+	closed          bool
+	lastPublishErr  error
+	lastPublishedAt time.Time
 }
 
-func NewWatermillPublisher[T any](brokers []string, logger appLogger.Logger) (pubsub.MessagePublisher[T], error) {
+// NewWatermillPublisher encodes payloads with codecs; a nil registry only supports JSON.
+func NewWatermillPublisher[T any](brokers []string, codecs *codec.Registry, logger appLogger.Logger) (pubsub.MessagePublisher[T], error) {
 	publisher, err := kafka.NewPublisher(
 		kafka.PublisherConfig{
 			Brokers:   brokers,
@@ -26,16 +48,48 @@ func NewWatermillPublisher[T any](brokers []string, logger appLogger.Logger) (pu
 	}
 
 	return &WatermillPublisher[T]{
-		publisher: publisher,
+		publisher:   publisher,
+		codecs:      codecs,
+		errorWindow: publishErrorWindow,
 	}, nil
 }
 
-func (w *WatermillPublisher[T]) Publish(ctx context.Context, topic string, pubsubMessages ...*pubsub.Message[T]) error {
+// WrapWatermillPublisher adapts any Watermill publisher, such as the in-memory one, to MessagePublisher.
+func WrapWatermillPublisher[T any](publisher message.Publisher, codecs *codec.Registry) pubsub.MessagePublisher[T] {
+	return &WatermillPublisher[T]{
+		publisher:   publisher,
+		codecs:      codecs,
+		errorWindow: publishErrorWindow,
+	}
+}
+
+func (w *WatermillPublisher[T]) Publish(ctx context.Context, topic string, pubsubMessages ...*pubsub.Message[T]) (err error) {
 	logger := appLogger.GetLoggerFromContext(ctx)
 
+	ctx, span := tracing.Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(len(pubsubMessages)),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	defer func() {
+		metrics.PublishDuration.WithLabelValues(topic, metrics.Result(err)).Observe(time.Since(start).Seconds())
+	}()
+
 	waterMillMessages := make([]*message.Message, 0, len(pubsubMessages))
 	for _, pubsubMessage := range pubsubMessages {
-		waterMillMessage, err := ConvertPubsubToWatermill(pubsubMessage, logger)
+		events.PropagateHeaders(ctx, &pubsubMessage.Headers)
+		// Messages relayed from the outbox keep the trace context of the handler that produced them.
+		if len(pubsubMessage.Headers.TraceContext) == 0 {
+			pubsubMessage.Headers.TraceContext = tracing.Inject(ctx)
+		}
+
+		waterMillMessage, err := ConvertPubsubToWatermill(ctx, topic, pubsubMessage, w.codecs, logger)
 		if err != nil {
 			logger.Error("Failed to convert pubsub message to watermill message",
 				"error", err.Error(),
@@ -47,7 +101,8 @@ func (w *WatermillPublisher[T]) Publish(ctx context.Context, topic string, pubsu
 		waterMillMessages = append(waterMillMessages, waterMillMessage)
 	}
 
-	err := w.publisher.Publish(topic, waterMillMessages...)
+	err = w.publisher.Publish(topic, waterMillMessages...)
+	w.recordPublish(err)
 	eventTypeKeyArr := getEventTypeKeyArray(waterMillMessages)
 
 	if err != nil {
@@ -80,6 +135,31 @@ func getEventTypeKeyArray(messages []*message.Message) []map[string]string {
 	return arr
 }
 
+// HealthCheck fails once the publisher is closed, or when its last publish failed within the error window.
+func (w *WatermillPublisher[T]) HealthCheck(ctx context.Context) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return errors.New("publisher is closed")
+	}
+	if w.lastPublishErr != nil && time.Since(w.lastPublishedAt) < w.errorWindow {
+		return fmt.Errorf("last publish failed: %w", w.lastPublishErr)
+	}
+	return nil
+}
+
+func (w *WatermillPublisher[T]) recordPublish(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastPublishErr = err
+	w.lastPublishedAt = time.Now()
+}
+
 func (w *WatermillPublisher[T]) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
 	return w.publisher.Close()
 }
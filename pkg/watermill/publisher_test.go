@@ -0,0 +1,52 @@
+package watermill
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Moreira-Henrique-Pedro/entregador/internal/domain/interfaces/pubsub"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+type stubPublisher struct {
+	err error
+}
+
+func (p *stubPublisher) Publish(string, ...*message.Message) error { return p.err }
+
+func (p *stubPublisher) Close() error { return nil }
+
+func TestWatermillPublisherHealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		publishErrs []error
+		errorWindow time.Duration
+		wantErr     bool
+	}{
+		{name: "no publish yet"},
+		{name: "last publish succeeded", publishErrs: []error{nil}, errorWindow: time.Minute},
+		{name: "last publish failed within the window", publishErrs: []error{errors.New("broker down")}, errorWindow: time.Minute, wantErr: true},
+		{name: "failure older than the window", publishErrs: []error{errors.New("broker down")}, errorWindow: 0},
+		{name: "failure followed by a success", publishErrs: []error{errors.New("broker down"), nil}, errorWindow: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubPublisher{}
+			publisher := WrapWatermillPublisher[any](stub, nil).(*WatermillPublisher[any])
+			publisher.errorWindow = tt.errorWindow
+
+			for _, publishErr := range tt.publishErrs {
+				stub.err = publishErr
+				msg := pubsub.NewMessage[any](context.Background(), pubsub.NewHeaders("event", "key"), map[string]string{})
+				_ = publisher.Publish(context.Background(), "topic", msg)
+			}
+
+			if err := publisher.HealthCheck(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("HealthCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}